@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/pmalek/github-pm-groomer/internal/issues"
@@ -9,10 +10,27 @@ import (
 )
 
 func decorateWithIssueSelector(cmd *cobra.Command, selector *issues.Selector) {
-	cmd.Flags().StringVar(&selector.Repo, "repo", "", "The <org>/<repo> to query")
-	cmd.Flags().StringVar(&selector.Labels, "labels", "", "A list of comma separated labels like: https://docs.github.com/en/rest/reference/issues#list-repository-issues")
-	cmd.Flags().StringVar(&selector.State, "state", "open", fmt.Sprintf("The state of the issue: open,closed,all"))
-	cmd.Flags().DurationVar(&selector.Since, "since", time.Duration(0), "Only apply to issues touched since")
-	cmd.Flags().IntVar(&selector.Limit, "limit", -1, "The max number of issues to return (-1 for all)")
-	cmd.Flags().StringVar(&selector.IssueList, "issues", "", "A comma separated list of issues to modify")
+	cmd.PersistentFlags().StringVar(&selector.Repo, "repo", "", "The <org>/<repo> to query")
+	cmd.PersistentFlags().StringVar(&selector.Labels, "labels", "", "A list of comma separated labels like: https://docs.github.com/en/rest/reference/issues#list-repository-issues")
+	cmd.PersistentFlags().StringVar(&selector.State, "state", "open", fmt.Sprintf("The state of the issue: open,closed,all"))
+	cmd.PersistentFlags().DurationVar(&selector.Since, "since", time.Duration(0), "Only apply to issues touched since")
+	cmd.PersistentFlags().IntVar(&selector.Limit, "limit", -1, "The max number of issues to return (-1 for all)")
+	cmd.PersistentFlags().StringVar(&selector.IssueList, "issues", "", "A comma separated list of issues to modify")
+}
+
+// useColor resolves a color option, auto colors only terminals and honors https://no-color.org.
+func useColor(option string) (bool, error) {
+	switch option {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		stat, err := os.Stdout.Stat()
+		return err == nil && stat.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid color option '%s' valid options: auto,always,never", option)
 }
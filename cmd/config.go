@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig holds the settings which can be stored in the CLI configuration file.
+type fileConfig struct {
+	BaseURL   string `yaml:"baseURL"`
+	UploadURL string `yaml:"uploadURL"`
+	CacheDir  string `yaml:"cacheDir"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "github-pm-groomer", "config.yaml")
+}
+
+// loadFileConfig reads the configuration file, a missing file is only an error when explicitly requested.
+func loadFileConfig(path string, explicit bool) (fileConfig, error) {
+	out := fileConfig{}
+	if path == "" {
+		return out, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return out, nil
+		}
+		return out, err
+	}
+	if err := yaml.Unmarshal(b, &out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// firstNonEmpty picks the setting with the highest precedence: flag, then environment, then configuration file.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
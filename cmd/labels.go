@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
 	"github.com/pmalek/github-pm-groomer/internal/labels"
 	"github.com/spf13/cobra"
 )
@@ -15,20 +17,25 @@ var (
 		Short: "do things to labels",
 		Long:  "Add or Remove labels, the will also apply to PRs",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := labelOpts.Validate(); err != nil {
-				return err
-			}
-			return labels.Run(cmd.Context(), ghClient, labelOpts, time.Now())
+			return runLabels(cmd.Context(), ghClient)
 		},
 	}
 	labelOpts labels.Opts
 )
 
+func runLabels(ctx context.Context, client api.Client) error {
+	if err := labelOpts.Validate(); err != nil {
+		return err
+	}
+	return labels.Run(ctx, client, labelOpts, time.Now())
+}
+
 func init() {
-	labelsCmd.Flags().StringVarP(&labelOpts.Action, "action", "a", "add", fmt.Sprintf("what to do on the issues (%s)", strings.Join(labels.AllOptions, ",")))
-	labelsCmd.Flags().StringVarP(&labelOpts.Label, "label", "l", "", "The label to add/remove")
-	labelsCmd.Flags().StringVar(&labelOpts.NewLabel, "new-label", "", "The new label name")
+	labelsCmd.PersistentFlags().StringVarP(&labelOpts.Action, "action", "a", "add", fmt.Sprintf("what to do on the issues (%s)", strings.Join(labels.AllOptions, ",")))
+	labelsCmd.PersistentFlags().StringVarP(&labelOpts.Label, "label", "l", "", "The label to add/remove")
+	labelsCmd.PersistentFlags().StringVar(&labelOpts.NewLabel, "new-label", "", "The new label name")
 	decorateWithIssueSelector(labelsCmd, &labelOpts.IssueSelector)
 
+	labelsCmd.AddCommand(newPlanCmd(runLabels))
 	rootCmd.AddCommand(labelsCmd)
 }
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
 	"github.com/pmalek/github-pm-groomer/internal/metasync"
+	"github.com/pmalek/github-pm-groomer/internal/plan"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -14,18 +21,194 @@ var (
 		Aliases: []string{"label-sync"},
 		Short:   "do things to repo metadata",
 		Long:    "Inspired by https://github.com/kubernetes/test-infra/tree/master/label_sync but with less options",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMetaSync(cmd.Context(), ghClient)
+		},
+	}
+	metaSyncOpts metasync.Opts
+
+	metaSyncDiffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show how repositories differ from the configuration",
+		Long:  "Print per repository and field what syncing would change. Exits with code 2 when any repository drifted and 1 on any other error.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			color, err := useColor(metaSyncDiffOpts.Color)
+			if err != nil {
+				return err
+			}
+			planner := plan.NewClient(ghClient)
+			if err := runMetaSync(cmd.Context(), planner); err != nil {
+				return err
+			}
+			if metasync.Diff(cmd.OutOrStdout(), planner.Plan("", time.Now()).Operations, color) {
+				// Drift isn't a usage problem.
+				cmd.SilenceUsage = true
+				return metasync.ErrDrift
+			}
+			return nil
+		},
+	}
+	metaSyncDiffOpts struct {
+		Color string
+	}
+
+	metaSyncValidateCmd = &cobra.Command{
+		Use:         "validate",
+		Short:       "Check the configuration without calling GitHub",
+		Long:        "Report invalid colors, too long descriptions, bad due dates, duplicate labels and milestones and malformed repositories with their file and line.",
+		Annotations: map[string]string{offlineAnnotation: "true"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := metaSyncOpts.Validate(); err != nil {
 				return err
 			}
-			return metasync.Run(cmd.Context(), ghClient, metaSyncOpts, time.Now())
+			diagnostics, err := metasync.Validate(metaSyncOpts.FilePath)
+			if err != nil {
+				return err
+			}
+			for _, d := range diagnostics {
+				fmt.Fprintln(cmd.OutOrStdout(), d)
+			}
+			if len(diagnostics) > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("found %d problems in the configuration", len(diagnostics))
+			}
+			return nil
+		},
+	}
+
+	metaSyncExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the labels and milestones of repositories as a configuration",
+		Long:  "Write the current labels and milestones of the repositories as a meta-sync configuration, what all of them share goes to config.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := metaSyncExportOpts.Validate(); err != nil {
+				return err
+			}
+			conf, err := metasync.Export(cmd.Context(), ghClient, metaSyncExportOpts.ExportOpts)
+			if err != nil {
+				return err
+			}
+			if metaSyncExportOpts.Output == "-" {
+				return metasync.WriteConf(cmd.OutOrStdout(), conf)
+			}
+			f, err := os.Create(metaSyncExportOpts.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := metasync.WriteConf(f, conf); err != nil {
+				return err
+			}
+			return f.Close()
+		},
+	}
+	metaSyncExportOpts struct {
+		metasync.ExportOpts
+		Output string
+	}
+
+	metaSyncIssueFormsCmd = &cobra.Command{
+		Use:         "issue-forms",
+		Short:       "Generate the issue forms of a repository without calling GitHub",
+		Long:        "Write the issue forms the configuration declares for the repository under the output directory, e.g. to review them before syncing.",
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := metaSyncOpts.Validate(); err != nil {
+				return err
+			}
+			if _, _, err := utils.OrgRepo(metaSyncIssueFormsOpts.Repo); err != nil {
+				return err
+			}
+			files, err := metasync.IssueForms(metaSyncOpts.FilePath, metaSyncIssueFormsOpts.Repo)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				p := filepath.Join(metaSyncIssueFormsOpts.Output, filepath.FromSlash(f.Path))
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(p, f.Content, 0o644); err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), p)
+			}
+			return nil
+		},
+	}
+	metaSyncIssueFormsOpts struct {
+		Repo   string
+		Output string
+	}
+
+	metaSyncCatalogCmd = &cobra.Command{
+		Use:         "catalog",
+		Short:       "Document the labels of the configuration",
+		Long:        "Write the labels grouped by prefix with their color, description and the repositories getting them, as Markdown or as a standalone HTML page. Patterns and topics are listed as they are.",
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := metaSyncOpts.Validate(); err != nil {
+				return err
+			}
+			if err := metaSyncCatalogOpts.Validate(); err != nil {
+				return err
+			}
+			if metaSyncCatalogOpts.Output == "-" {
+				return metasync.WriteCatalog(cmd.OutOrStdout(), metaSyncOpts.FilePath, metaSyncCatalogOpts.CatalogOpts)
+			}
+			f, err := os.Create(metaSyncCatalogOpts.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := metasync.WriteCatalog(f, metaSyncOpts.FilePath, metaSyncCatalogOpts.CatalogOpts); err != nil {
+				return err
+			}
+			return f.Close()
+		},
+	}
+	metaSyncCatalogOpts struct {
+		metasync.CatalogOpts
+		Output string
+	}
+
+	metaSyncSchemaCmd = &cobra.Command{
+		Use:         "schema",
+		Short:       "Print the JSON Schema of the configuration",
+		Long:        "Print the JSON Schema of the configuration for editors, e.g. with a `# yaml-language-server: $schema=meta-sync.schema.json` comment.",
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := cmd.OutOrStdout().Write(metasync.Schema)
+			return err
 		},
 	}
-	metaSyncOpts metasync.Opts
 )
 
+func runMetaSync(ctx context.Context, client api.Client) error {
+	if err := metaSyncOpts.Validate(); err != nil {
+		return err
+	}
+	return metasync.Run(ctx, client, metaSyncOpts, time.Now())
+}
+
 func init() {
-	metaSyncCmd.Flags().StringVarP(&metaSyncOpts.FilePath, "path", "p", "", "The path or url to the labels to sync")
-	metaSyncCmd.Flags().IntVarP(&metaSyncOpts.Concurrency, "concurrency", "c", runtime.NumCPU(), "The number of concurrent goroutines to use for syncing metadata")
+	metaSyncCmd.PersistentFlags().StringVarP(&metaSyncOpts.FilePath, "path", "p", "", "The path or url to the labels to sync")
+	metaSyncCmd.PersistentFlags().IntVarP(&metaSyncOpts.Concurrency, "concurrency", "c", runtime.NumCPU(), "The number of concurrent goroutines to use for syncing metadata")
+	metaSyncCmd.PersistentFlags().BoolVar(&metaSyncOpts.Prune, "prune", false, "Delete the labels and milestones missing from the configuration, unless a repository disables it")
+	metaSyncCmd.AddCommand(newPlanCmd(runMetaSync))
+	metaSyncDiffCmd.Flags().StringVar(&metaSyncDiffOpts.Color, "color", "auto", "When to color the output (auto,always,never)")
+	metaSyncCmd.AddCommand(metaSyncDiffCmd)
+	metaSyncCmd.AddCommand(metaSyncValidateCmd)
+	metaSyncCmd.AddCommand(metaSyncSchemaCmd)
+	metaSyncExportCmd.Flags().StringSliceVar(&metaSyncExportOpts.Repos, "repo", nil, "The org/repo to export, can be repeated")
+	metaSyncExportCmd.Flags().StringSliceVar(&metaSyncExportOpts.Orgs, "org", nil, "The org whose repositories are all exported, can be repeated")
+	metaSyncExportCmd.Flags().StringVarP(&metaSyncExportOpts.Output, "output", "o", "-", "The file to write the configuration to, - for stdout")
+	metaSyncCmd.AddCommand(metaSyncExportCmd)
+	metaSyncIssueFormsCmd.Flags().StringVar(&metaSyncIssueFormsOpts.Repo, "repo", "", "The org/repo to generate the issue forms of")
+	metaSyncIssueFormsCmd.Flags().StringVarP(&metaSyncIssueFormsOpts.Output, "output", "o", ".", "The directory to write .github/ISSUE_TEMPLATE to")
+	metaSyncCmd.AddCommand(metaSyncIssueFormsCmd)
+	metaSyncCatalogCmd.Flags().StringVar(&metaSyncCatalogOpts.Format, "format", metasync.CatalogMarkdown, "The format of the catalog (markdown,html)")
+	metaSyncCatalogCmd.Flags().StringVarP(&metaSyncCatalogOpts.Output, "output", "o", "-", "The file to write the catalog to, - for stdout")
+	metaSyncCmd.AddCommand(metaSyncCatalogCmd)
 	rootCmd.AddCommand(metaSyncCmd)
 }
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/plan"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyCmd = &cobra.Command{
+		Use:   "apply <plan.json>",
+		Short: "Apply a plan saved by a plan command",
+		Long:  "Apply exactly the changes of a plan, refusing to do anything if an object it targets changed since planning.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := plan.Read(args[0])
+			if err != nil {
+				return err
+			}
+			return plan.Apply(cmd.Context(), ghClient, p)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
+
+// newPlanCmd returns a plan subcommand for a command, run does what the command does with the given client.
+// The flags of the command must be persistent so that the subcommand gets them too.
+func newPlanCmd(run func(ctx context.Context, client api.Client) error) *cobra.Command {
+	output := ""
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Save the changes which would be made to a plan file, to apply later",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			planner := plan.NewClient(ghClient)
+			if err := run(cmd.Context(), planner); err != nil {
+				return err
+			}
+			p := planner.Plan(strings.Join(os.Args[1:], " "), time.Now())
+			if err := plan.Write(output, p); err != nil {
+				return err
+			}
+			slog.LogAttrs(cmd.Context(), slog.LevelInfo, "plan saved", slog.String("path", output), slog.Int("operations", len(p.Operations)))
+			return nil
+		},
+	}
+	planCmd.Flags().StringVarP(&output, "output", "o", "plan.json", "The file to save the plan to")
+	return planCmd
+}
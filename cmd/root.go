@@ -2,24 +2,191 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/github/dryrun"
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+	"github.com/pmalek/github-pm-groomer/internal/journal"
 	"github.com/spf13/cobra"
 )
 
+const (
+	githubBackend = "github"
+	fakeBackend   = "fake"
+
+	restIssuesAPI    = "rest"
+	graphqlIssuesAPI = "graphql"
+
+	// offlineAnnotation marks commands which don't need a GitHub client.
+	offlineAnnotation = "offline"
+)
+
 var (
 	rootCmd = &cobra.Command{
 		Use:   "github-pm-groomer",
 		Short: "A CLI to do common product management stuff on github",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Annotations[offlineAnnotation] == "true" {
+				return nil
+			}
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			clientCloser, _ = client.(io.Closer)
+			if rootOpts.DryRun {
+				dryRunClient = dryrun.New(client)
+				client = dryRunClient
+			} else if rootOpts.JournalDir != "" {
+				client, err = journal.New(client, rootOpts.JournalDir, journal.NewRunID(time.Now()))
+				if err != nil {
+					return err
+				}
+			}
+			ghClient = client
+			return ghClient.Ping(cmd.Context())
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if dryRunClient != nil {
+				dryRunClient.LogSummary(cmd.Context())
+			}
+		},
+	}
+	ghClient     api.Client
+	dryRunClient *dryrun.Client
+	// clientCloser releases what the client holds, such as the cassette being recorded.
+	clientCloser io.Closer
+	rootOpts     struct {
+		Config     string
+		BaseURL    string
+		UploadURL  string
+		Backend    string
+		Fixture    string
+		Record     string
+		Replay     string
+		AppID      int64
+		AppKey     string
+		IssuesAPI  string
+		CacheDir   string
+		DryRun     bool
+		JournalDir string
 	}
-	ghClient api.Client
 )
 
-func Execute(ctx context.Context) error {
-	ghClient = api.New(os.Getenv("GITHUB_API_TOKEN"))
-	if err := ghClient.Ping(ctx); err != nil {
-		return err
+func init() {
+	// Finalizers run even when the command fails, unlike PersistentPostRun.
+	cobra.OnFinalize(closeClient)
+	rootCmd.PersistentFlags().StringVar(&rootOpts.JournalDir, "journal-dir", "", "A directory to journal the changes of each run in to be able to undo them, journaling reads the state of every changed object first")
+	rootCmd.PersistentFlags().BoolVar(&rootOpts.DryRun, "dry-run", false, "Only log the changes which would be made to GitHub")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.Config, "config", defaultConfigPath(), "The CLI configuration file")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.BaseURL, "base-url", "", "The GitHub Enterprise Server API URL (defaults to GITHUB_API_URL)")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.UploadURL, "upload-url", "", "The GitHub Enterprise Server upload URL (defaults to GITHUB_UPLOAD_URL or the base URL)")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.Backend, "backend", githubBackend, fmt.Sprintf("The backend to run against (%s,%s)", githubBackend, fakeBackend))
+	rootCmd.PersistentFlags().StringVar(&rootOpts.Fixture, "fixture", "", "The YAML or JSON file to seed the fake backend with")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.IssuesAPI, "issues-api", restIssuesAPI, fmt.Sprintf("The API used to list issues (%s,%s), %s is much faster on large repositories but skips pull requests", restIssuesAPI, graphqlIssuesAPI, graphqlIssuesAPI))
+	rootCmd.PersistentFlags().StringVar(&rootOpts.CacheDir, "cache-dir", "", "A directory to cache GitHub responses in, unchanged data is then revalidated without using the rate limit")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.Record, "record", "", "Record every GitHub API interaction to this cassette file")
+	rootCmd.PersistentFlags().Int64Var(&rootOpts.AppID, "app-id", 0, "The GitHub App ID to authenticate as (defaults to GITHUB_APP_ID)")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.AppKey, "app-private-key", "", "The path to the GitHub App private key PEM (defaults to GITHUB_APP_PRIVATE_KEY_PATH)")
+	rootCmd.PersistentFlags().StringVar(&rootOpts.Replay, "replay", "", "Replay GitHub API interactions from this cassette file instead of calling GitHub")
+}
+
+func closeClient() {
+	if clientCloser == nil {
+		return
+	}
+	if err := clientCloser.Close(); err != nil {
+		slog.LogAttrs(context.Background(), slog.LevelError, "failed to close the client", slog.Any("error", err))
+	}
+	clientCloser = nil
+}
+
+func newClient(cmd *cobra.Command) (api.Client, error) {
+	switch rootOpts.Backend {
+	case githubBackend:
+		conf, err := loadFileConfig(rootOpts.Config, cmd.Flags().Changed("config"))
+		if err != nil {
+			return nil, err
+		}
+		opts := api.Opts{
+			Token:      os.Getenv("GITHUB_API_TOKEN"),
+			BaseURL:    firstNonEmpty(rootOpts.BaseURL, os.Getenv("GITHUB_API_URL"), conf.BaseURL),
+			UploadURL:  firstNonEmpty(rootOpts.UploadURL, os.Getenv("GITHUB_UPLOAD_URL"), conf.UploadURL),
+			CacheDir:   firstNonEmpty(rootOpts.CacheDir, conf.CacheDir),
+			RecordPath: rootOpts.Record,
+			ReplayPath: rootOpts.Replay,
+		}
+		switch rootOpts.IssuesAPI {
+		case restIssuesAPI:
+		case graphqlIssuesAPI:
+			opts.GraphQLIssues = true
+		default:
+			return nil, fmt.Errorf("invalid issues api '%s' valid options: %s,%s", rootOpts.IssuesAPI, restIssuesAPI, graphqlIssuesAPI)
+		}
+		if err := appAuth(&opts); err != nil {
+			return nil, err
+		}
+		return api.New(opts)
+	case fakeBackend:
+		fixture := fake.Fixture{}
+		if rootOpts.Fixture != "" {
+			var err error
+			fixture, err = fake.LoadFixture(rootOpts.Fixture)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return fake.New(fixture), nil
+	default:
+		return nil, fmt.Errorf("invalid backend '%s' valid options: %s,%s", rootOpts.Backend, githubBackend, fakeBackend)
 	}
+}
+
+// appAuth switches to GitHub App authentication when an app ID is configured, the private key is read from
+// a file or from the GITHUB_APP_PRIVATE_KEY variable holding the PEM itself.
+func appAuth(opts *api.Opts) error {
+	id := rootOpts.AppID
+	if id == 0 && os.Getenv("GITHUB_APP_ID") != "" {
+		var err error
+		id, err = strconv.ParseInt(os.Getenv("GITHUB_APP_ID"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid GITHUB_APP_ID: %w", err)
+		}
+	}
+	if id == 0 {
+		return nil
+	}
+
+	key := []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	keyPath := rootOpts.AppKey
+	if keyPath == "" {
+		keyPath = os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH")
+	}
+	if keyPath != "" {
+		var err error
+		key, err = os.ReadFile(keyPath)
+		if err != nil {
+			return err
+		}
+	}
+	if len(key) == 0 {
+		return errors.New("a private key is required to authenticate as a GitHub App")
+	}
+
+	// The app identity replaces the personal token.
+	opts.Token = ""
+	opts.AppID = id
+	opts.AppPrivateKey = key
+	return nil
+}
+
+func Execute(ctx context.Context) error {
 	return rootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/pmalek/github-pm-groomer/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoCmd = &cobra.Command{
+		Use:   "undo",
+		Short: "Revert the changes made by a previous run",
+		Long:  "Apply the inverse of every change journaled by a previous run with --journal-dir, last change first. Changes to objects modified since the run are skipped. Comments can't be reverted and deleted labels are recreated without their issues.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if undoOpts.Run == "" {
+				return errors.New("must set the run to undo")
+			}
+			if rootOpts.JournalDir == "" {
+				return errors.New("must set the --journal-dir the run was journaled in")
+			}
+			ops, err := journal.Read(rootOpts.JournalDir, undoOpts.Run)
+			if err != nil {
+				return err
+			}
+			return journal.Undo(cmd.Context(), ghClient, ops)
+		},
+	}
+	undoOpts struct {
+		Run string
+	}
+)
+
+func init() {
+	undoCmd.Flags().StringVar(&undoOpts.Run, "run", "", "The id of the run to undo, as logged when it started journaling")
+	rootCmd.AddCommand(undoCmd)
+}
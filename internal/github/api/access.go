@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+// Permissions are the repository roles from the least to the most privileged.
+var Permissions = []string{"read", "triage", "write", "maintain", "admin"}
+
+// Access is the permission (one of Permissions) a team or a user has on a repository.
+type Access struct {
+	// Name is the slug of a team or the login of a user.
+	Name       string
+	Permission string
+	// Pending is set for the users invited to become a collaborator who haven't accepted yet.
+	Pending bool
+}
+
+// apiPermissions maps the roles to the names the REST API takes.
+var apiPermissions = map[string]string{
+	"read":     "pull",
+	"triage":   "triage",
+	"write":    "push",
+	"maintain": "maintain",
+	"admin":    "admin",
+}
+
+// highestPermission picks the role from the permissions flags the REST API returns.
+func highestPermission(flags map[string]bool) string {
+	for i := len(Permissions) - 1; i >= 0; i-- {
+		if flags[apiPermissions[Permissions[i]]] {
+			return Permissions[i]
+		}
+	}
+	return ""
+}
+
+func (gc *githubClient) ListTeamAccess(ctx context.Context, orgRepo string) ([]*Access, error) {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	var res []*Access
+	for i := 1; ; i++ {
+		teams, _, err := gc.client.Repositories.ListTeams(ctx, org, repo, &github.ListOptions{PerPage: 100, Page: i})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range teams {
+			res = append(res, &Access{Name: t.GetSlug(), Permission: highestPermission(t.Permissions)})
+		}
+		if len(teams) < 100 {
+			return res, nil
+		}
+	}
+}
+
+func (gc *githubClient) SetTeamAccess(ctx context.Context, orgRepo string, team string, permission string) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	_, err := gc.client.Teams.AddTeamRepoBySlug(ctx, org, team, org, repo, &github.TeamAddTeamRepoOptions{Permission: apiPermissions[permission]})
+	return err
+}
+
+func (gc *githubClient) RemoveTeamAccess(ctx context.Context, orgRepo string, team string) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	_, err := gc.client.Teams.RemoveTeamRepoBySlug(ctx, org, team, org, repo)
+	return err
+}
+
+func (gc *githubClient) ListCollaboratorAccess(ctx context.Context, orgRepo string) ([]*Access, error) {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	var res []*Access
+	for i := 1; ; i++ {
+		users, _, err := gc.client.Repositories.ListCollaborators(ctx, org, repo, &github.ListCollaboratorsOptions{
+			// Organization members granted access to the repository itself are collaborators too, outside would leave
+			// them out and all would list everyone with access through the organization or a team.
+			Affiliation: "direct",
+			ListOptions: github.ListOptions{PerPage: 100, Page: i},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			res = append(res, &Access{Name: u.GetLogin(), Permission: highestPermission(u.Permissions)})
+		}
+		if len(users) < 100 {
+			break
+		}
+	}
+
+	// Invitees aren't collaborators until they accept, they're listed so they don't get invited again.
+	invitations, err := gc.listInvitations(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range invitations {
+		res = append(res, &Access{Name: i.GetInvitee().GetLogin(), Permission: invitationPermission(i.GetPermissions()), Pending: true})
+	}
+	return res, nil
+}
+
+// listInvitations returns the pending invitations of the repository, expired ones are left out.
+func (gc *githubClient) listInvitations(ctx context.Context, org string, repo string) ([]*github.RepositoryInvitation, error) {
+	var res []*github.RepositoryInvitation
+	for i := 1; ; i++ {
+		invitations, _, err := gc.client.Repositories.ListInvitations(ctx, org, repo, &github.ListOptions{PerPage: 100, Page: i})
+		if err != nil {
+			return nil, err
+		}
+		for _, invitation := range invitations {
+			if !invitation.GetExpired() {
+				res = append(res, invitation)
+			}
+		}
+		if len(invitations) < 100 {
+			return res, nil
+		}
+	}
+}
+
+// findInvitation returns the pending invitation of login, nil if there's none.
+func (gc *githubClient) findInvitation(ctx context.Context, org string, repo string, login string) (*github.RepositoryInvitation, error) {
+	invitations, err := gc.listInvitations(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range invitations {
+		if strings.EqualFold(i.GetInvitee().GetLogin(), login) {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+
+// invitationPermission maps the permission of an invitation, which can use the names of the REST API, to a role.
+func invitationPermission(permission string) string {
+	for role, name := range apiPermissions {
+		if name == permission {
+			return role
+		}
+	}
+	return permission
+}
+
+// SetCollaboratorAccess invites users who aren't collaborators yet and changes the permission of the pending
+// invitations.
+func (gc *githubClient) SetCollaboratorAccess(ctx context.Context, orgRepo string, login string, permission string) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	invitation, err := gc.findInvitation(ctx, org, repo, login)
+	if err != nil {
+		return err
+	}
+	if invitation != nil {
+		_, _, err := gc.client.Repositories.UpdateInvitation(ctx, org, repo, invitation.GetID(), permission)
+		return err
+	}
+	_, _, err = gc.client.Repositories.AddCollaborator(ctx, org, repo, login, &github.RepositoryAddCollaboratorOptions{Permission: apiPermissions[permission]})
+	return err
+}
+
+// RemoveCollaboratorAccess cancels the pending invitation of login if there's one.
+func (gc *githubClient) RemoveCollaboratorAccess(ctx context.Context, orgRepo string, login string) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	invitation, err := gc.findInvitation(ctx, org, repo, login)
+	if err != nil {
+		return err
+	}
+	if invitation != nil {
+		_, err := gc.client.Repositories.DeleteInvitation(ctx, org, repo, invitation.GetID())
+		return err
+	}
+	_, err = gc.client.Repositories.RemoveCollaborator(ctx, org, repo, login)
+	return err
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// invitationServer serves a repository with the outside collaborator alice, the organization member dave granted
+// access to the repository, erin who only has access through a team and a pending invitation of bob. It records
+// the mutating requests.
+func invitationServer(t *testing.T, requests *[]string) *httptest.Server {
+	t.Helper()
+	alice := map[string]any{"login": "alice", "permissions": map[string]bool{"pull": true, "push": true}}
+	dave := map[string]any{"login": "dave", "permissions": map[string]bool{"pull": true, "triage": true}}
+	erin := map[string]any{"login": "erin", "permissions": map[string]bool{"pull": true}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A base URL is taken for GitHub Enterprise, which serves the API under /api/v3.
+		path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+		switch {
+		case r.Method == http.MethodGet && path == "/repos/org/repo/collaborators":
+			users := map[string][]map[string]any{
+				"outside": {alice},
+				"direct":  {alice, dave},
+				"all":     {alice, dave, erin},
+			}[r.URL.Query().Get("affiliation")]
+			_ = json.NewEncoder(w).Encode(users)
+		case r.Method == http.MethodGet && path == "/repos/org/repo/invitations":
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"id": 7, "invitee": map[string]any{"login": "bob"}, "permissions": "read"},
+				{"id": 8, "invitee": map[string]any{"login": "carol"}, "permissions": "write", "expired": true},
+			})
+		default:
+			*requests = append(*requests, r.Method+" "+path)
+			_, _ = w.Write([]byte("{}"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCollaboratorInvitations(t *testing.T) {
+	var requests []string
+	client, err := New(Opts{BaseURL: invitationServer(t, &requests).URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	access, err := client.ListCollaboratorAccess(ctx, "org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Access
+	for _, a := range access {
+		got = append(got, *a)
+	}
+	want := []Access{
+		{Name: "alice", Permission: "write"},
+		{Name: "dave", Permission: "triage"},
+		{Name: "bob", Permission: "read", Pending: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{
+			name: "change the permission of an invitation",
+			call: func() error { return client.SetCollaboratorAccess(ctx, "org/repo", "Bob", "write") },
+			want: "PATCH /repos/org/repo/invitations/7",
+		},
+		{
+			name: "change the permission of a collaborator",
+			call: func() error { return client.SetCollaboratorAccess(ctx, "org/repo", "alice", "read") },
+			want: "PUT /repos/org/repo/collaborators/alice",
+		},
+		{
+			name: "invite again once expired",
+			call: func() error { return client.SetCollaboratorAccess(ctx, "org/repo", "carol", "write") },
+			want: "PUT /repos/org/repo/collaborators/carol",
+		},
+		{
+			name: "cancel an invitation",
+			call: func() error { return client.RemoveCollaboratorAccess(ctx, "org/repo", "bob") },
+			want: "DELETE /repos/org/repo/invitations/7",
+		},
+		{
+			name: "change the permission of an organization member",
+			call: func() error { return client.SetCollaboratorAccess(ctx, "org/repo", "dave", "maintain") },
+			want: "PUT /repos/org/repo/collaborators/dave",
+		},
+		{
+			name: "remove a collaborator",
+			call: func() error { return client.RemoveCollaboratorAccess(ctx, "org/repo", "alice") },
+			want: "DELETE /repos/org/repo/collaborators/alice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(requests, []string{tt.want}) {
+				t.Errorf("got requests %q, want %q", requests, tt.want)
+			}
+		})
+	}
+}
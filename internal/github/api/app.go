@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+)
+
+const (
+	// GitHub refuses JWTs valid for more than 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// Installation tokens last an hour, refresh them a bit before they expire so in-flight requests don't fail.
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// appJWT signs the JWTs authenticating as the GitHub App itself.
+type appJWT struct {
+	id  int64
+	key *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newAppJWT(id int64, privateKey []byte) (*appJWT, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("app private key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err8 != nil {
+			return nil, fmt.Errorf("invalid app private key: %w", err)
+		}
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, errors.New("app private key must be an RSA key")
+		}
+	}
+	return &appJWT{id: id, key: key}, nil
+}
+
+func (a *appJWT) get(now time.Time) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && now.Before(a.expires.Add(-time.Minute)) {
+		return a.token, nil
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	expires := now.Add(jwtLifetime)
+	claims, err := json.Marshal(map[string]any{
+		// Backdated to allow for clock drift with GitHub.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": expires.Unix(),
+		"iss": strconv.FormatInt(a.id, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	a.token = unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	a.expires = expires
+	return a.token, nil
+}
+
+// jwtTransport authenticates requests as the app, this is only allowed on the /app endpoints.
+type jwtTransport struct {
+	jwt  *appJWT
+	base http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.jwt.get(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+type installationToken struct {
+	token   string
+	expires time.Time
+}
+
+// installationTransport authenticates each request with a token of the installation of the app on the owner of
+// the targeted repository or organization. Installations are discovered and their tokens refreshed on demand so
+// a single client can work across many organizations.
+type installationTransport struct {
+	apps *github.Client
+	base http.RoundTripper
+
+	mu            sync.Mutex
+	installations map[string]int64
+	tokens        map[int64]installationToken
+}
+
+// newInstallationTransport needs apps to be authenticated with the app JWT.
+func newInstallationTransport(apps *github.Client, base http.RoundTripper) *installationTransport {
+	return &installationTransport{
+		apps:          apps,
+		base:          base,
+		installations: map[string]int64{},
+		tokens:        map[int64]installationToken{},
+	}
+}
+
+type ownerKey struct{}
+
+type ownerRepo struct {
+	owner string
+	repo  string
+}
+
+// withOwner tells which owner a request targets when its path doesn't, like GraphQL queries.
+func withOwner(ctx context.Context, owner string, repo string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, ownerRepo{owner: owner, repo: repo})
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	owner, repo := ownerFromPath(req.URL.Path)
+	if o, ok := req.Context().Value(ownerKey{}).(ownerRepo); ok && owner == "" {
+		owner, repo = o.owner, o.repo
+	}
+	if owner == "" {
+		// Not scoped to an owner (e.g. /licenses), these work without authentication.
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.token(req.Context(), owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+token)
+	return t.base.RoundTrip(req)
+}
+
+func (t *installationTransport) token(ctx context.Context, owner string, repo string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := strings.ToLower(owner)
+	id, ok := t.installations[key]
+	if !ok {
+		var installation *github.Installation
+		var err error
+		if repo != "" {
+			installation, _, err = t.apps.Apps.FindRepositoryInstallation(ctx, owner, repo)
+		} else {
+			installation, _, err = t.apps.Apps.FindOrganizationInstallation(ctx, owner)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to find the app installation for %s: %w", owner, err)
+		}
+		id = installation.GetID()
+		t.installations[key] = id
+	}
+
+	if cur, ok := t.tokens[id]; ok && time.Now().Before(cur.expires.Add(-tokenRefreshMargin)) {
+		return cur.token, nil
+	}
+	token, _, err := t.apps.Apps.CreateInstallationToken(ctx, id, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an installation token for %s: %w", owner, err)
+	}
+	t.tokens[id] = installationToken{token: token.GetToken(), expires: token.GetExpiresAt().Time}
+	return token.GetToken(), nil
+}
+
+// ownerFromPath extracts the owner (and repository if any) targeted by a REST API path.
+func ownerFromPath(path string) (string, string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		switch parts[i] {
+		case "repos":
+			if i+2 < len(parts) {
+				return parts[i+1], parts[i+2]
+			}
+			return parts[i+1], ""
+		case "orgs":
+			return parts[i+1], ""
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		pem     []byte
+		wantErr bool
+	}{
+		{name: "pkcs1", pem: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})},
+		{name: "pkcs8", pem: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})},
+		{name: "not pem", pem: []byte("secret"), wantErr: true},
+		{name: "not a key", pem: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("secret")}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwt, err := newAppJWT(42, tt.pem)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := jwt.get(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := strings.Split(token, ".")
+			if len(parts) != 3 {
+				t.Fatalf("token has %d parts, want 3", len(parts))
+			}
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+			hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+				t.Errorf("invalid signature: %v", err)
+			}
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims struct {
+				Iat int64  `json:"iat"`
+				Exp int64  `json:"exp"`
+				Iss string `json:"iss"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims.Iss != "42" || claims.Iat != now.Add(-time.Minute).Unix() || claims.Exp != now.Add(jwtLifetime).Unix() {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+
+			if again, _ := jwt.get(now.Add(time.Minute)); again != token {
+				t.Error("token not reused before it expires")
+			}
+			if renewed, _ := jwt.get(now.Add(jwtLifetime)); renewed == token {
+				t.Error("token reused after it expired")
+			}
+		})
+	}
+}
+
+func TestAppTokenNotRecorded(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const token = "ghs_installationsecret"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := strings.TrimPrefix(r.URL.Path, "/api/v3"); path {
+		case "/repos/org/repo/installation":
+			_, _ = w.Write([]byte(`{"id": 7}`))
+		case "/app/installations/7/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"token": %q, "expires_at": %q}`, token, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case "/repos/org/repo/labels":
+			if r.Header.Get("Authorization") != "token "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`[{"name": "bug"}]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	cassette := filepath.Join(t.TempDir(), "cassette.jsonl")
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	client, err := New(Opts{BaseURL: srv.URL + "/", AppID: 42, AppPrivateKey: privateKey, RecordPath: cassette})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListLabels(context.Background(), "org/repo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), token) || !strings.Contains(string(b), "access_tokens") {
+		t.Errorf("the cassette holds the installation token:\n%s", b)
+	}
+
+	// The redacted token is enough to replay.
+	srv.Close()
+	client, err = New(Opts{BaseURL: srv.URL + "/", AppID: 42, AppPrivateKey: privateKey, ReplayPath: cassette})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels, err := client.ListLabels(context.Background(), "org/repo"); err != nil || len(labels) != 1 {
+		t.Errorf("replay got %v: %v", labels, err)
+	}
+}
@@ -2,17 +2,23 @@ package api
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gofri/go-github-ratelimit/github_ratelimit"
 	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/cassette"
+	"github.com/pmalek/github-pm-groomer/internal/github/httpcache"
 	"github.com/pmalek/github-pm-groomer/internal/utils"
 	"golang.org/x/oauth2"
 )
 
 const IssuesPerPage = 100
 
+const defaultBaseURL = "https://api.github.com/"
+
 type Client interface {
 	GetIssue(ctx context.Context, orgRepo string, issue int) (*Issue, error)
 	GetIssues(ctx context.Context, orgRepo string, options IssueListOptions, page int) ([]*Issue, error)
@@ -28,35 +34,167 @@ type Client interface {
 	UpdateMilestone(ctx context.Context, orgRepo string, number int, milestone *Milestone) error
 	DeleteMilestone(ctx context.Context, orgRepo string, number int) error
 	CreateMilestone(ctx context.Context, repo string, milestone *Milestone) error
+	ListRepositories(ctx context.Context, org string) ([]*Repository, error)
+	GetRepository(ctx context.Context, orgRepo string) (*Repository, error)
+	// UpdateRepository only changes the fields which are set, including the topics.
+	UpdateRepository(ctx context.Context, orgRepo string, repository *Repository) error
+	// ListRulesets returns the rulesets defined on the repository itself with all their rules.
+	ListRulesets(ctx context.Context, orgRepo string) ([]*Ruleset, error)
+	CreateRuleset(ctx context.Context, orgRepo string, ruleset *Ruleset) error
+	UpdateRuleset(ctx context.Context, orgRepo string, id int64, ruleset *Ruleset) error
+	DeleteRuleset(ctx context.Context, orgRepo string, id int64) error
+	ListTeamAccess(ctx context.Context, orgRepo string) ([]*Access, error)
+	SetTeamAccess(ctx context.Context, orgRepo string, team string, permission string) error
+	RemoveTeamAccess(ctx context.Context, orgRepo string, team string) error
+	// ListCollaboratorAccess lists the users granted access to the repository itself, organization members included,
+	// and the users invited to become a collaborator. Access through the organization or a team isn't listed.
+	ListCollaboratorAccess(ctx context.Context, orgRepo string) ([]*Access, error)
+	SetCollaboratorAccess(ctx context.Context, orgRepo string, login string, permission string) error
+	RemoveCollaboratorAccess(ctx context.Context, orgRepo string, login string) error
+	// GetFile returns nil when the file doesn't exist, ref defaults to the default branch.
+	GetFile(ctx context.Context, orgRepo string, path string, ref string) (*File, error)
+	ProposeFiles(ctx context.Context, orgRepo string, proposal *FileProposal) error
 }
 
 type githubClient struct {
 	client *github.Client
+	// apps is only set when authenticated as a GitHub App.
+	apps *github.Client
+	// enterprise is set when talking to a GitHub Enterprise Server.
+	enterprise bool
+	// recorder is only set when recording a cassette.
+	recorder *cassette.Recorder
+}
+
+type Opts struct {
+	Token string
+	// BaseURL and UploadURL point to a GitHub Enterprise Server, api.github.com is used when empty.
+	BaseURL   string
+	UploadURL string
+	// AppID and AppPrivateKey authenticate as a GitHub App using installation tokens instead of Token.
+	AppID         int64
+	AppPrivateKey []byte
+	// GraphQLIssues lists issues with the GraphQL API, much lighter on large repositories.
+	GraphQLIssues bool
+	// CacheDir is where responses are cached to revalidate them with conditional requests, unused when replaying.
+	CacheDir string
+	// RecordPath is a cassette file where every interaction with the API gets recorded.
+	RecordPath string
+	// ReplayPath is a cassette file to answer requests from instead of calling the API.
+	ReplayPath string
 }
 
-func New(token string) Client {
+func New(opts Opts) (Client, error) {
+	if opts.RecordPath != "" && opts.ReplayPath != "" {
+		return nil, errors.New("can't record and replay at the same time")
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if opts.CacheDir != "" && opts.ReplayPath == "" {
+		cache, err := httpcache.New(opts.CacheDir, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = cache
+	}
+
+	// The cassette sits outside the cache so it records the responses as the client sees them, revalidated ones
+	// included, and a replay never depends on what's cached.
+	var recorder *cassette.Recorder
+	switch {
+	case opts.RecordPath != "":
+		var err error
+		recorder, err = cassette.NewRecorder(opts.RecordPath, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = recorder
+	case opts.ReplayPath != "":
+		replayer, err := cassette.NewReplayer(opts.ReplayPath)
+		if err != nil {
+			return nil, err
+		}
+		transport = replayer
+	}
+
+	var apps *github.Client
+	var err error
+	if opts.AppID != 0 {
+		if opts.Token != "" {
+			return nil, errors.New("can't authenticate with both a token and a GitHub App")
+		}
+		jwt, err := newAppJWT(opts.AppID, opts.AppPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		apps, err = opts.newGithubClient(&http.Client{Transport: &jwtTransport{jwt: jwt, base: transport}})
+		if err != nil {
+			return nil, err
+		}
+		transport = newInstallationTransport(apps, transport)
+	} else if opts.Token != "" {
+		transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.Token}),
+			Base:   transport,
+		}
+	}
+
 	// https://github.com/google/go-github?tab=readme-ov-file#rate-limiting
-	rateLimiter, err := github_ratelimit.NewRateLimitWaiterClient(nil)
+	rateLimiter, err := github_ratelimit.NewRateLimitWaiterClient(transport)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	client := github.NewClient(rateLimiter)
-	if token != "" {
-		ctx := context.Background()
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		tc := oauth2.NewClient(ctx, ts)
-		client = github.NewClient(tc)
+	client, err := opts.newGithubClient(rateLimiter)
+	if err != nil {
+		return nil, err
+	}
+	gc := &githubClient{
+		client:     client,
+		apps:       apps,
+		enterprise: opts.enterprise(),
+		recorder:   recorder,
+	}
+	if opts.GraphQLIssues {
+		return newGraphqlClient(gc, rateLimiter), nil
 	}
+	return gc, nil
+}
 
-	return &githubClient{
-		client: client,
+// Close releases the cassette being recorded, the client must not be used anymore afterwards.
+func (gc *githubClient) Close() error {
+	if gc.recorder == nil {
+		return nil
 	}
+	return gc.recorder.Close()
+}
+
+func (o Opts) enterprise() bool {
+	return o.BaseURL != "" && strings.TrimSuffix(o.BaseURL, "/") != strings.TrimSuffix(defaultBaseURL, "/")
+}
+
+func (o Opts) newGithubClient(httpClient *http.Client) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if !o.enterprise() {
+		return client, nil
+	}
+	uploadURL := o.UploadURL
+	if uploadURL == "" {
+		uploadURL = o.BaseURL
+	}
+	return client.WithEnterpriseURLs(o.BaseURL, uploadURL)
 }
 
 func (gc *githubClient) Ping(ctx context.Context) error {
+	if gc.apps != nil {
+		// Checks the app credentials as the license endpoint doesn't need any.
+		_, _, err := gc.apps.Apps.Get(ctx, "")
+		return err
+	}
+	if gc.enterprise {
+		// The licenses API isn't available on all GitHub Enterprise Server versions, meta always is.
+		_, _, err := gc.client.Meta.Get(ctx)
+		return err
+	}
 	_, _, err := gc.client.Licenses.Get(ctx, "MIT")
 	return err
 }
@@ -65,6 +203,8 @@ type IssueListOptions struct {
 	Labels string
 	State  string
 	Since  time.Time
+	// IncludePullRequests makes listings which skip pull requests, such as the GraphQL one, use the REST API.
+	IncludePullRequests bool
 }
 
 func (gc *githubClient) UpdateLabels(ctx context.Context, orgRepo string, issue int, labels []string) error {
@@ -120,10 +260,14 @@ func (gc *githubClient) Comment(ctx context.Context, orgRepo string, issueNumber
 
 type Label github.Label
 
+func (l *Label) GetName() string        { return (*github.Label)(l).GetName() }
+func (l *Label) GetColor() string       { return (*github.Label)(l).GetColor() }
+func (l *Label) GetDescription() string { return (*github.Label)(l).GetDescription() }
+
 func (gc *githubClient) ListLabels(ctx context.Context, orgRepo string) ([]*Label, error) {
 	org, repo := utils.MustOrgRepo(orgRepo)
 	var allLabels []*Label
-	for i := 0; ; i++ {
+	for i := 1; ; i++ {
 		labels, _, err := gc.client.Issues.ListLabels(ctx, org, repo, &github.ListOptions{PerPage: 100, Page: i})
 		if err != nil {
 			return nil, err
@@ -157,10 +301,15 @@ func (gc *githubClient) CreateLabel(ctx context.Context, orgRepo string, label *
 
 type Milestone github.Milestone
 
+func (m *Milestone) GetNumber() int         { return (*github.Milestone)(m).GetNumber() }
+func (m *Milestone) GetTitle() string       { return (*github.Milestone)(m).GetTitle() }
+func (m *Milestone) GetState() string       { return (*github.Milestone)(m).GetState() }
+func (m *Milestone) GetDescription() string { return (*github.Milestone)(m).GetDescription() }
+
 func (gc *githubClient) ListMilestones(ctx context.Context, orgRepo string) ([]*Milestone, error) {
 	org, repo := utils.MustOrgRepo(orgRepo)
 	var allMilestones []*Milestone
-	for i := 0; ; i++ {
+	for i := 1; ; i++ {
 		milestones, _, err := gc.client.Issues.ListMilestones(ctx, org, repo, &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100, Page: i}})
 		if err != nil {
 			return nil, err
@@ -191,3 +340,92 @@ func (gc *githubClient) CreateMilestone(ctx context.Context, orgRepo string, mil
 	_, _, err := gc.client.Issues.CreateMilestone(ctx, org, repo, (*github.Milestone)(milestone))
 	return err
 }
+
+type Repository github.Repository
+
+func (r *Repository) GetName() string          { return (*github.Repository)(r).GetName() }
+func (r *Repository) GetFullName() string      { return (*github.Repository)(r).GetFullName() }
+func (r *Repository) GetArchived() bool        { return (*github.Repository)(r).GetArchived() }
+func (r *Repository) GetFork() bool            { return (*github.Repository)(r).GetFork() }
+func (r *Repository) GetDefaultBranch() string { return (*github.Repository)(r).GetDefaultBranch() }
+
+func (gc *githubClient) ListRepositories(ctx context.Context, org string) ([]*Repository, error) {
+	var allRepos []*Repository
+	for i := 1; ; i++ {
+		repos, _, err := gc.client.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{Type: "all", ListOptions: github.ListOptions{PerPage: 100, Page: i}})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			allRepos = append(allRepos, (*Repository)(r))
+		}
+		if len(repos) < 100 {
+			return allRepos, nil
+		}
+	}
+}
+
+func (gc *githubClient) GetRepository(ctx context.Context, orgRepo string) (*Repository, error) {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	r, _, err := gc.client.Repositories.Get(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	return (*Repository)(r), nil
+}
+
+func (gc *githubClient) UpdateRepository(ctx context.Context, orgRepo string, repository *Repository) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	// Topics have their own endpoint.
+	edit := *(*github.Repository)(repository)
+	edit.Topics = nil
+	if _, _, err := gc.client.Repositories.Edit(ctx, org, repo, &edit); err != nil {
+		return err
+	}
+	if repository.Topics != nil {
+		if _, _, err := gc.client.Repositories.ReplaceAllTopics(ctx, org, repo, repository.Topics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Ruleset github.Ruleset
+
+func (r *Ruleset) GetID() int64 { return (*github.Ruleset)(r).GetID() }
+
+func (gc *githubClient) ListRulesets(ctx context.Context, orgRepo string) ([]*Ruleset, error) {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	rulesets, _, err := gc.client.Repositories.GetAllRulesets(ctx, org, repo, false)
+	if err != nil {
+		return nil, err
+	}
+	// The listing leaves the rules out.
+	var res []*Ruleset
+	for _, r := range rulesets {
+		full, _, err := gc.client.Repositories.GetRuleset(ctx, org, repo, r.GetID(), false)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, (*Ruleset)(full))
+	}
+	return res, nil
+}
+
+func (gc *githubClient) CreateRuleset(ctx context.Context, orgRepo string, ruleset *Ruleset) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	_, _, err := gc.client.Repositories.CreateRuleset(ctx, org, repo, (*github.Ruleset)(ruleset))
+	return err
+}
+
+func (gc *githubClient) UpdateRuleset(ctx context.Context, orgRepo string, id int64, ruleset *Ruleset) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	_, _, err := gc.client.Repositories.UpdateRuleset(ctx, org, repo, id, (*github.Ruleset)(ruleset))
+	return err
+}
+
+func (gc *githubClient) DeleteRuleset(ctx context.Context, orgRepo string, id int64) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	_, err := gc.client.Repositories.DeleteRuleset(ctx, org, repo, id)
+	return err
+}
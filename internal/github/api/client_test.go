@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pagedServer answers list requests like GitHub does: a missing page or page 0 is the first page.
+func pagedServer(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page = max(page, 1)
+		var items []map[string]any
+		for i := (page - 1) * perPage; i < min(page*perPage, total); i++ {
+			items = append(items, map[string]any{"number": i + 1, "name": fmt.Sprintf("label-%d", i), "title": fmt.Sprintf("milestone-%d", i)})
+		}
+		if items == nil {
+			items = []map[string]any{}
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPagination(t *testing.T) {
+	for _, total := range []int{0, 1, 100, 101, 250} {
+		t.Run(strconv.Itoa(total), func(t *testing.T) {
+			srv := pagedServer(t, total)
+			client, err := New(Opts{BaseURL: srv.URL + "/"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels, err := client.ListLabels(context.Background(), "org/repo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(labels) != total {
+				t.Errorf("got %d labels, want %d", len(labels), total)
+			}
+			milestones, err := client.ListMilestones(context.Background(), "org/repo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(milestones) != total {
+				t.Errorf("got %d milestones, want %d", len(milestones), total)
+			}
+		})
+	}
+}
+
+func TestCassetteOutsideCache(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, `[{"name": "bug"}]`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	cassette := filepath.Join(dir, "cassette.jsonl")
+
+	client, err := New(Opts{BaseURL: srv.URL + "/", CacheDir: filepath.Join(dir, "cache"), RecordPath: cassette})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListLabels(context.Background(), "org/repo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), `"statusCode":304`) || strings.Count(string(b), `\"bug\"`) != 2 {
+		t.Errorf("the cassette doesn't have the revalidated responses:\n%s", b)
+	}
+
+	srv.Close()
+	client, err = New(Opts{BaseURL: srv.URL + "/", CacheDir: filepath.Join(dir, "cache"), ReplayPath: cassette})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		labels, err := client.ListLabels(context.Background(), "org/repo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(labels) != 1 || labels[0].GetName() != "bug" {
+			t.Errorf("replay %d got %v", i, labels)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+}
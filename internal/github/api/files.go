@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+// File is the content of a file in a repository.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// FileProposal proposes changes to files with a pull request from Branch to the default branch.
+type FileProposal struct {
+	Branch string
+	// Message is the commit message of each changed file.
+	Message string
+	Title   string
+	Body    string
+	Files   []File
+}
+
+func isNotFound(resp *github.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+func (gc *githubClient) GetFile(ctx context.Context, orgRepo string, path string, ref string) (*File, error) {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	content, _, resp, err := gc.client.Repositories.GetContents(ctx, org, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if isNotFound(resp) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, errors.New(path + " is a directory")
+	}
+	decoded, err := content.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return &File{Path: path, Content: []byte(decoded)}, nil
+}
+
+// ProposeFiles commits the files which differ to Branch and opens a pull request from it. While a pull request is
+// open for the branch, the branch is updated in place so it can be called again while it's pending. Otherwise the
+// branch is reset to the default branch first so it's never stale. A pull request closed without merging isn't
+// reopened nor proposed again while the branch holds the same files, changed files are proposed in a new one.
+func (gc *githubClient) ProposeFiles(ctx context.Context, orgRepo string, proposal *FileProposal) error {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	r, _, err := gc.client.Repositories.Get(ctx, org, repo)
+	if err != nil {
+		return err
+	}
+	base := r.GetDefaultBranch()
+
+	prs, _, err := gc.client.PullRequests.List(ctx, org, repo, &github.PullRequestListOptions{
+		State:       "all",
+		Head:        org + ":" + proposal.Branch,
+		Sort:        "created",
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return err
+	}
+	var latest *github.PullRequest
+	if len(prs) > 0 {
+		latest = prs[0]
+	}
+	open := latest != nil && latest.GetState() == "open"
+
+	_, resp, err := gc.client.Git.GetRef(ctx, org, repo, "heads/"+proposal.Branch)
+	exists := !isNotFound(resp)
+	if err != nil && exists {
+		return err
+	}
+	if !open {
+		if exists && latest != nil && latest.MergedAt == nil {
+			declined, err := gc.sameFiles(ctx, org, repo, proposal)
+			if err != nil || declined {
+				if declined {
+					slog.LogAttrs(ctx, slog.LevelWarn, "not proposing files again as their pull request was closed",
+						slog.String("repo", orgRepo), slog.Int("number", latest.GetNumber()))
+				}
+				return err
+			}
+		}
+		baseRef, _, err := gc.client.Git.GetRef(ctx, org, repo, "heads/"+base)
+		if err != nil {
+			return err
+		}
+		ref := &github.Reference{Ref: github.String("refs/heads/" + proposal.Branch), Object: baseRef.Object}
+		if exists {
+			_, _, err = gc.client.Git.UpdateRef(ctx, org, repo, ref, true)
+		} else {
+			_, _, err = gc.client.Git.CreateRef(ctx, org, repo, ref)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range proposal.Files {
+		cur, _, resp, err := gc.client.Repositories.GetContents(ctx, org, repo, f.Path, &github.RepositoryContentGetOptions{Ref: proposal.Branch})
+		if err != nil && !isNotFound(resp) {
+			return err
+		}
+		opts := &github.RepositoryContentFileOptions{
+			Message: github.String(proposal.Message),
+			Content: f.Content,
+			Branch:  github.String(proposal.Branch),
+		}
+		if cur != nil {
+			content, err := cur.GetContent()
+			if err != nil {
+				return err
+			}
+			if content == string(f.Content) {
+				continue
+			}
+			opts.SHA = cur.SHA
+		}
+		if _, _, err := gc.client.Repositories.UpdateFile(ctx, org, repo, f.Path, opts); err != nil {
+			return err
+		}
+	}
+
+	if open {
+		return nil
+	}
+	_, _, err = gc.client.PullRequests.Create(ctx, org, repo, &github.NewPullRequest{
+		Title: github.String(proposal.Title),
+		Head:  github.String(proposal.Branch),
+		Base:  github.String(base),
+		Body:  github.String(proposal.Body),
+	})
+	return err
+}
+
+// sameFiles tells whether the branch of proposal already holds its files.
+func (gc *githubClient) sameFiles(ctx context.Context, org string, repo string, proposal *FileProposal) (bool, error) {
+	for _, f := range proposal.Files {
+		cur, err := gc.GetFile(ctx, org+"/"+repo, f.Path, proposal.Branch)
+		if err != nil || cur == nil || !bytes.Equal(cur.Content, f.Content) {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// proposalServer serves a repository whose meta-sync/files branch holds CODEOWNERS and whose last pull request
+// from it is pr, nil for none. It records the mutating requests.
+func proposalServer(t *testing.T, pr map[string]any, requests *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A base URL is taken for GitHub Enterprise, which serves the API under /api/v3.
+		path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+		if r.Method != http.MethodGet {
+			*requests = append(*requests, r.Method+" "+path)
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+		var res any
+		switch path {
+		case "/repos/org/repo":
+			res = map[string]any{"default_branch": "main"}
+		case "/repos/org/repo/pulls":
+			res = []any{}
+			if pr != nil {
+				res = []any{pr}
+			}
+		case "/repos/org/repo/git/ref/heads/main", "/repos/org/repo/git/ref/heads/meta-sync/files":
+			res = map[string]any{"object": map[string]any{"sha": "abc"}}
+		case "/repos/org/repo/contents/CODEOWNERS":
+			res = map[string]any{
+				"type": "file", "encoding": "base64", "sha": "def",
+				"content": base64.StdEncoding.EncodeToString([]byte("* @org/old")),
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			res = map[string]any{"message": "Not Found"}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProposeFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		pr      map[string]any
+		content string
+		want    []string
+	}{
+		{
+			name:    "no pull request",
+			content: "* @org/new",
+			want: []string{
+				"PATCH /repos/org/repo/git/refs/heads/meta-sync/files",
+				"PUT /repos/org/repo/contents/CODEOWNERS",
+				"POST /repos/org/repo/pulls",
+			},
+		},
+		{
+			name:    "open pull request",
+			pr:      map[string]any{"number": 1, "state": "open"},
+			content: "* @org/new",
+			want:    []string{"PUT /repos/org/repo/contents/CODEOWNERS"},
+		},
+		{
+			name:    "merged pull request",
+			pr:      map[string]any{"number": 1, "state": "closed", "merged_at": "2026-01-01T00:00:00Z"},
+			content: "* @org/old",
+			want: []string{
+				"PATCH /repos/org/repo/git/refs/heads/meta-sync/files",
+				"POST /repos/org/repo/pulls",
+			},
+		},
+		{
+			name:    "closed pull request with the same files",
+			pr:      map[string]any{"number": 1, "state": "closed"},
+			content: "* @org/old",
+		},
+		{
+			name:    "closed pull request with other files",
+			pr:      map[string]any{"number": 1, "state": "closed"},
+			content: "* @org/new",
+			want: []string{
+				"PATCH /repos/org/repo/git/refs/heads/meta-sync/files",
+				"PUT /repos/org/repo/contents/CODEOWNERS",
+				"POST /repos/org/repo/pulls",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			client, err := New(Opts{BaseURL: proposalServer(t, tt.pr, &requests).URL + "/"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = client.ProposeFiles(context.Background(), "org/repo", &FileProposal{
+				Branch: "meta-sync/files",
+				Files:  []File{{Path: "CODEOWNERS", Content: []byte(tt.content)}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(requests, tt.want) {
+				t.Errorf("got requests %q, want %q", requests, tt.want)
+			}
+		})
+	}
+}
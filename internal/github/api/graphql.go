@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+// issuesQuery only fetches what the selectors and the lifecycle rules look at.
+const issuesQuery = `query($owner: String!, $name: String!, $first: Int!, $after: String, $states: [IssueState!], $filterBy: IssueFilters) {
+  repository(owner: $owner, name: $name) {
+    issues(first: $first, after: $after, states: $states, filterBy: $filterBy, orderBy: {field: CREATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        title
+        state
+        createdAt
+        updatedAt
+        closedAt
+        labels(first: 100) { nodes { name color description } }
+        assignees(first: 100) { nodes { login } }
+        comments { totalCount }
+      }
+    }
+  }
+}`
+
+type graphqlIssue struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	State     string     `json:"state"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	ClosedAt  *time.Time `json:"closedAt"`
+	Labels    struct {
+		Nodes []struct {
+			Name        string `json:"name"`
+			Color       string `json:"color"`
+			Description string `json:"description"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Assignees struct {
+		Nodes []struct {
+			Login string `json:"login"`
+		} `json:"nodes"`
+	} `json:"assignees"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+}
+
+type issuesPage struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []graphqlIssue `json:"nodes"`
+}
+
+type graphqlIssuesResponse struct {
+	Data struct {
+		Repository *struct {
+			Issues issuesPage `json:"issues"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphqlClient lists issues with the GraphQL API which returns only the needed fields, everything else goes
+// through the REST client. Unlike the REST API pull requests are not listed.
+type graphqlClient struct {
+	*githubClient
+	http     *http.Client
+	endpoint string
+
+	mu sync.Mutex
+	// cursors maps a listing and a page to the cursor to start that page from.
+	cursors map[string]*string
+}
+
+func newGraphqlClient(gc *githubClient, httpClient *http.Client) *graphqlClient {
+	endpoint := gc.client.BaseURL.ResolveReference(&url.URL{Path: "graphql"}).String()
+	if gc.enterprise {
+		// GitHub Enterprise Server serves GraphQL at /api/graphql instead of /api/v3/graphql.
+		endpoint = gc.client.BaseURL.ResolveReference(&url.URL{Path: "../graphql"}).String()
+	}
+	return &graphqlClient{
+		githubClient: gc,
+		http:         httpClient,
+		endpoint:     endpoint,
+		cursors:      map[string]*string{},
+	}
+}
+
+func cursorKey(orgRepo string, options IssueListOptions, page int) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d", orgRepo, options.Labels, options.State, options.Since.Format(time.RFC3339), page)
+}
+
+// GetIssues keeps the paging semantics of the REST API by remembering where each page ends, pages are expected
+// to be requested in order but earlier pages are walked again if needed.
+func (gc *graphqlClient) GetIssues(ctx context.Context, orgRepo string, options IssueListOptions, page int) ([]*Issue, error) {
+	if options.IncludePullRequests {
+		return gc.githubClient.GetIssues(ctx, orgRepo, options, page)
+	}
+	if page < 1 {
+		page = 1
+	}
+	cursor := ""
+	if page > 1 {
+		gc.mu.Lock()
+		c, ok := gc.cursors[cursorKey(orgRepo, options, page)]
+		gc.mu.Unlock()
+		if !ok {
+			if _, err := gc.GetIssues(ctx, orgRepo, options, page-1); err != nil {
+				return nil, err
+			}
+			gc.mu.Lock()
+			c = gc.cursors[cursorKey(orgRepo, options, page)]
+			gc.mu.Unlock()
+		}
+		if c == nil {
+			// The previous page was the last one.
+			return nil, nil
+		}
+		cursor = *c
+	}
+
+	var labels []string
+	for _, l := range strings.Split(options.Labels, ",") {
+		if l != "" {
+			labels = append(labels, l)
+		}
+	}
+	for {
+		resp, err := gc.queryIssues(ctx, orgRepo, options, labels, cursor)
+		if err != nil {
+			return nil, err
+		}
+		var res []*Issue
+		for _, n := range resp.Nodes {
+			if i := n.toIssue(); hasAllLabels(i, labels) {
+				res = append(res, i)
+			}
+		}
+		var next *string
+		if resp.PageInfo.HasNextPage {
+			next = &resp.PageInfo.EndCursor
+		}
+		// An empty page means the end of the listing for callers so keep going until something matches.
+		if len(res) == 0 && next != nil {
+			cursor = *next
+			continue
+		}
+		gc.mu.Lock()
+		gc.cursors[cursorKey(orgRepo, options, page+1)] = next
+		gc.mu.Unlock()
+		return res, nil
+	}
+}
+
+// hasAllLabels is needed as the GraphQL label filter matches issues with any of the labels, REST with all of them.
+func hasAllLabels(issue *Issue, labels []string) bool {
+	for _, l := range labels {
+		found := false
+		for _, il := range issue.Labels {
+			if strings.EqualFold(il.GetName(), l) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (gc *graphqlClient) queryIssues(ctx context.Context, orgRepo string, options IssueListOptions, labels []string, cursor string) (*issuesPage, error) {
+	org, repo := utils.MustOrgRepo(orgRepo)
+	variables := map[string]any{
+		"owner": org,
+		"name":  repo,
+		"first": IssuesPerPage,
+	}
+	if cursor != "" {
+		variables["after"] = cursor
+	}
+	switch options.State {
+	case "", "open":
+		variables["states"] = []string{"OPEN"}
+	case "closed":
+		variables["states"] = []string{"CLOSED"}
+	}
+	filterBy := map[string]any{}
+	if len(labels) > 0 {
+		filterBy["labels"] = labels
+	}
+	if !options.Since.IsZero() {
+		filterBy["since"] = options.Since.Format(time.RFC3339)
+	}
+	variables["filterBy"] = filterBy
+
+	body, err := json.Marshal(map[string]any{"query": issuesQuery, "variables": variables})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(withOwner(ctx, org, repo), http.MethodPost, gc.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r, err := gc.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("graphql query failed with status code: %d", r.StatusCode)
+	}
+	out := graphqlIssuesResponse{}
+	if err := json.NewDecoder(r.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.Errors) > 0 {
+		var msgs []string
+		for _, e := range out.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return nil, fmt.Errorf("graphql query failed: %s", strings.Join(msgs, ", "))
+	}
+	if out.Data.Repository == nil {
+		return nil, errors.New("graphql query failed: repository not found")
+	}
+	return &out.Data.Repository.Issues, nil
+}
+
+func (n graphqlIssue) toIssue() *Issue {
+	i := &Issue{
+		Number:    github.Int(n.Number),
+		Title:     github.String(n.Title),
+		State:     github.String(strings.ToLower(n.State)),
+		CreatedAt: &github.Timestamp{Time: n.CreatedAt},
+		UpdatedAt: &github.Timestamp{Time: n.UpdatedAt},
+		Comments:  github.Int(n.Comments.TotalCount),
+	}
+	if n.ClosedAt != nil {
+		i.ClosedAt = &github.Timestamp{Time: *n.ClosedAt}
+	}
+	for _, l := range n.Labels.Nodes {
+		i.Labels = append(i.Labels, &github.Label{
+			Name:        github.String(l.Name),
+			Color:       github.String(l.Color),
+			Description: github.String(l.Description),
+		})
+	}
+	for _, a := range n.Assignees.Nodes {
+		i.Assignees = append(i.Assignees, &github.User{Login: github.String(a.Login)})
+	}
+	return i
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGraphqlGetIssues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/graphql"):
+			_, _ = io.WriteString(w, `{"data": {"repository": {"issues": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"number": 1, "title": "issue", "state": "OPEN", "labels": {"nodes": [{"name": "bug"}]}}
+			]}}}}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/repos/org/repo/issues"):
+			_, _ = io.WriteString(w, `[
+				{"number": 1, "title": "issue", "state": "open", "labels": [{"name": "bug"}]},
+				{"number": 2, "title": "pull request", "state": "open", "labels": [{"name": "bug"}], "pull_request": {}}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client, err := New(Opts{BaseURL: srv.URL + "/", GraphQLIssues: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options IssueListOptions
+		want    int
+	}{
+		{name: "graphql", options: IssueListOptions{Labels: "bug", State: "all"}, want: 1},
+		{name: "with pull requests", options: IssueListOptions{Labels: "bug", State: "all", IncludePullRequests: true}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := client.GetIssues(context.Background(), "org/repo", tt.options, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != tt.want {
+				t.Errorf("got %d issues, want %d", len(issues), tt.want)
+			}
+		})
+	}
+}
+
+// graphqlPages serves the issues 1 and 2, then 3, then nothing, it records the cursor each query starts after.
+func graphqlPages(t *testing.T, after *[]string) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"":   `{"pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "nodes": [{"number": 1, "state": "OPEN"}, {"number": 2, "state": "OPEN"}]}`,
+		"c1": `{"pageInfo": {"hasNextPage": true, "endCursor": "c2"}, "nodes": [{"number": 3, "state": "OPEN"}]}`,
+		"c2": `{"pageInfo": {"hasNextPage": false, "endCursor": "c3"}, "nodes": []}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query struct {
+			Variables struct {
+				After string `json:"after"`
+			} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			t.Error(err)
+		}
+		*after = append(*after, query.Variables.After)
+		page, ok := pages[query.Variables.After]
+		if !ok {
+			t.Errorf("unexpected cursor %q", query.Variables.After)
+		}
+		_, _ = io.WriteString(w, `{"data": {"repository": {"issues": `+page+`}}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGraphqlGetIssuesPages(t *testing.T) {
+	type request struct {
+		page int
+		// wantAfter are the cursors of the queries sent for the page.
+		wantAfter []string
+		want      []int
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "in order",
+			requests: []request{
+				{page: 1, wantAfter: []string{""}, want: []int{1, 2}},
+				{page: 2, wantAfter: []string{"c1"}, want: []int{3}},
+				{page: 3, wantAfter: []string{"c2"}},
+				// The previous page was the last one.
+				{page: 4},
+			},
+		},
+		{
+			name: "out of order",
+			requests: []request{
+				{page: 2, wantAfter: []string{"", "c1"}, want: []int{3}},
+				{page: 1, wantAfter: []string{""}, want: []int{1, 2}},
+				{page: 3, wantAfter: []string{"c2"}},
+			},
+		},
+		{
+			name: "past the end",
+			requests: []request{
+				{page: 5, wantAfter: []string{"", "c1", "c2"}},
+				{page: 2, wantAfter: []string{"c1"}, want: []int{3}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var after []string
+			client, err := New(Opts{BaseURL: graphqlPages(t, &after).URL + "/", GraphQLIssues: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range tt.requests {
+				after = nil
+				issues, err := client.GetIssues(context.Background(), "org/repo", IssueListOptions{}, r.page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []int
+				for _, i := range issues {
+					got = append(got, *i.Number)
+				}
+				if !slices.Equal(got, r.want) {
+					t.Errorf("page %d got issues %v, want %v", r.page, got, r.want)
+				}
+				if !slices.Equal(after, r.wantAfter) {
+					t.Errorf("page %d queried after %q, want %q", r.page, after, r.wantAfter)
+				}
+			}
+		})
+	}
+}
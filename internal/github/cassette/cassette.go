@@ -0,0 +1,197 @@
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Interaction is a single request/response exchanged with the API, a cassette is a JSONL file of interactions.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request holds what identifies a request, headers are left out so credentials never end up in a cassette.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+func (r Request) key() string {
+	return r.Method + " " + r.URL + "\n" + r.Body
+}
+
+func newRequest(req *http.Request) (Request, error) {
+	res := Request{Method: req.Method, URL: req.URL.String()}
+	if req.Body == nil || req.Body == http.NoBody {
+		return res, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	if err != nil {
+		return res, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	res.Body = string(b)
+	return res, nil
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewBufferString(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// Recorder is a http.RoundTripper appending every interaction going through it to a cassette, tokens in responses
+// are redacted.
+type Recorder struct {
+	base http.RoundTripper
+	mu   sync.Mutex
+	out  *os.File
+}
+
+func NewRecorder(path string, base http.RoundTripper) (*Recorder, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{base: base, out: out}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	line, err := json.Marshal(Interaction{
+		Request:  recorded,
+		Response: Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: redact(b)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.out.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to record interaction: %w", err)
+	}
+	return resp, nil
+}
+
+// Close flushes the cassette to disk, interactions can't be recorded anymore afterwards.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.out.Sync(); err != nil {
+		_ = r.out.Close()
+		return err
+	}
+	return r.out.Close()
+}
+
+// redactedToken replaces the tokens handed out by the API in cassettes.
+const redactedToken = "REDACTED"
+
+// redact removes the token of responses such as the GitHub App installation tokens, replays only need the token
+// to be there.
+func redact(body []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+	var token string
+	if err := json.Unmarshal(fields["token"], &token); err != nil || token == "" {
+		return string(body)
+	}
+	fields["token"], _ = json.Marshal(redactedToken)
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
+
+// Replayer is a http.RoundTripper answering requests from a cassette without any network access.
+// Identical requests get their recorded responses in the order they were recorded.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]Response
+}
+
+func NewReplayer(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &Replayer{interactions: map[string][]Response{}}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s:%d: %w", path, line, err)
+		}
+		k := i.Request.key()
+		r.interactions[k] = append(r.interactions[k], i.Response)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := recorded.key()
+	responses := r.interactions[k]
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no recorded interaction left for %s %s", recorded.Method, recorded.URL)
+	}
+	r.interactions[k] = responses[1:]
+	return responses[0].toHTTP(req), nil
+}
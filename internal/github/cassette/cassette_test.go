@@ -0,0 +1,152 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type call struct {
+	method string
+	path   string
+	body   string
+}
+
+func (c call) request(t *testing.T, base string) *http.Request {
+	t.Helper()
+	var body io.Reader
+	if c.body != "" {
+		body = strings.NewReader(c.body)
+	}
+	req, err := http.NewRequest(c.method, base+c.path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// record sends the calls through a Recorder to a server numbering its responses, it returns the cassette path and
+// the URL the calls were sent to.
+func record(t *testing.T, calls ...call) (string, string) {
+	t.Helper()
+	var served int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Served", strconv.Itoa(served))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, r.Method+" "+r.URL.Path+" "+string(b))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	recorder, err := NewRecorder(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range calls {
+		resp, err := recorder.RoundTrip(c.request(t, srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The recorded response can still be read by the caller.
+		if b, err := io.ReadAll(resp.Body); err != nil || len(b) == 0 {
+			t.Fatalf("got body %q: %v", b, err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path, srv.URL
+}
+
+func TestReplay(t *testing.T) {
+	path, base := record(t,
+		call{method: http.MethodGet, path: "/repos/org/repo/labels"},
+		call{method: http.MethodPost, path: "/repos/org/repo/labels", body: `{"name":"bug"}`},
+		call{method: http.MethodGet, path: "/repos/org/repo/labels"},
+	)
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		call    call
+		want    string
+		wantErr string
+	}{
+		{
+			name: "first of identical requests",
+			call: call{method: http.MethodGet, path: "/repos/org/repo/labels"},
+			want: "201 1 GET /repos/org/repo/labels ",
+		},
+		{
+			name:    "body mismatch",
+			call:    call{method: http.MethodPost, path: "/repos/org/repo/labels", body: `{"name":"feature"}`},
+			wantErr: "no recorded interaction left for POST " + base + "/repos/org/repo/labels",
+		},
+		{
+			name: "body match",
+			call: call{method: http.MethodPost, path: "/repos/org/repo/labels", body: `{"name":"bug"}`},
+			want: `201 2 POST /repos/org/repo/labels {"name":"bug"}`,
+		},
+		{
+			name: "second of identical requests",
+			call: call{method: http.MethodGet, path: "/repos/org/repo/labels"},
+			want: "201 3 GET /repos/org/repo/labels ",
+		},
+		{
+			name:    "identical requests exhausted",
+			call:    call{method: http.MethodGet, path: "/repos/org/repo/labels"},
+			wantErr: "no recorded interaction left for GET " + base + "/repos/org/repo/labels",
+		},
+		{
+			name:    "never recorded",
+			call:    call{method: http.MethodDelete, path: "/repos/org/repo/labels/bug"},
+			wantErr: "no recorded interaction left for DELETE " + base + "/repos/org/repo/labels/bug",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := replayer.RoundTrip(tt.call.request(t, base))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strconv.Itoa(resp.StatusCode) + " " + resp.Header.Get("X-Served") + " " + string(b); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderClose(t *testing.T) {
+	recorder, err := NewRecorder(filepath.Join(t.TempDir(), "cassette.jsonl"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	if _, err := recorder.RoundTrip(call{method: http.MethodGet, path: "/meta"}.request(t, srv.URL)); err == nil {
+		t.Error("recorded an interaction once closed")
+	}
+}
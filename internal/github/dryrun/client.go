@@ -0,0 +1,217 @@
+package dryrun
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+// Client wraps an api.Client, reads go through while mutations are only logged and counted. It doesn't embed the
+// client so a method added to api.Client can't reach GitHub before it's handled here.
+type Client struct {
+	client api.Client
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+var _ api.Client = &Client{}
+
+func New(client api.Client) *Client {
+	return &Client{
+		client: client,
+		counts: map[string]int{},
+	}
+}
+
+func (c *Client) skip(ctx context.Context, op string, attrs ...slog.Attr) {
+	c.mu.Lock()
+	c.counts[op] += 1
+	c.mu.Unlock()
+	slog.LogAttrs(ctx, slog.LevelInfo, "dry-run: skipping "+op, attrs...)
+}
+
+// Counts returns how many times each mutation was skipped.
+func (c *Client) Counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make(map[string]int, len(c.counts))
+	for k, v := range c.counts {
+		res[k] = v
+	}
+	return res
+}
+
+// LogSummary logs the number of skipped mutations.
+func (c *Client) LogSummary(ctx context.Context) {
+	counts := c.Counts()
+	ops := make([]string, 0, len(counts))
+	total := 0
+	for op, n := range counts {
+		ops = append(ops, op)
+		total += n
+	}
+	sort.Strings(ops)
+	attrs := []slog.Attr{slog.Int("total", total)}
+	for _, op := range ops {
+		attrs = append(attrs, slog.Int(strings.ReplaceAll(op, " ", "_"), counts[op]))
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "dry-run: summary", attrs...)
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx)
+}
+
+func (c *Client) GetIssue(ctx context.Context, orgRepo string, issue int) (*api.Issue, error) {
+	return c.client.GetIssue(ctx, orgRepo, issue)
+}
+
+func (c *Client) GetIssues(ctx context.Context, orgRepo string, options api.IssueListOptions, page int) ([]*api.Issue, error) {
+	return c.client.GetIssues(ctx, orgRepo, options, page)
+}
+
+func (c *Client) ListLabels(ctx context.Context, orgRepo string) ([]*api.Label, error) {
+	return c.client.ListLabels(ctx, orgRepo)
+}
+
+func (c *Client) ListMilestones(ctx context.Context, orgRepo string) ([]*api.Milestone, error) {
+	return c.client.ListMilestones(ctx, orgRepo)
+}
+
+func (c *Client) ListRepositories(ctx context.Context, org string) ([]*api.Repository, error) {
+	return c.client.ListRepositories(ctx, org)
+}
+
+func (c *Client) GetRepository(ctx context.Context, orgRepo string) (*api.Repository, error) {
+	return c.client.GetRepository(ctx, orgRepo)
+}
+
+func (c *Client) ListRulesets(ctx context.Context, orgRepo string) ([]*api.Ruleset, error) {
+	return c.client.ListRulesets(ctx, orgRepo)
+}
+
+func (c *Client) ListTeamAccess(ctx context.Context, orgRepo string) ([]*api.Access, error) {
+	return c.client.ListTeamAccess(ctx, orgRepo)
+}
+
+func (c *Client) ListCollaboratorAccess(ctx context.Context, orgRepo string) ([]*api.Access, error) {
+	return c.client.ListCollaboratorAccess(ctx, orgRepo)
+}
+
+func (c *Client) GetFile(ctx context.Context, orgRepo string, path string, ref string) (*api.File, error) {
+	return c.client.GetFile(ctx, orgRepo, path, ref)
+}
+
+func (c *Client) UpdateLabels(ctx context.Context, orgRepo string, issue int, labels []string) error {
+	c.skip(ctx, "update labels", slog.String("repo", orgRepo), slog.Int("issue", issue), slog.String("labels", strings.Join(labels, ",")))
+	return nil
+}
+
+func (c *Client) UpdateIssueState(ctx context.Context, orgRepo string, issue int, state string) error {
+	c.skip(ctx, "update issue state", slog.String("repo", orgRepo), slog.Int("issue", issue), slog.String("state", state))
+	return nil
+}
+
+func (c *Client) Comment(ctx context.Context, orgRepo string, issueNumber int, message string) error {
+	c.skip(ctx, "comment", slog.String("repo", orgRepo), slog.Int("issue", issueNumber), slog.String("message", message))
+	return nil
+}
+
+func labelAttrs(label *api.Label) []slog.Attr {
+	l := (*github.Label)(label)
+	return []slog.Attr{slog.String("label", l.GetName()), slog.String("color", l.GetColor()), slog.String("description", l.GetDescription())}
+}
+
+func (c *Client) UpdateLabel(ctx context.Context, orgRepo string, originalName string, label *api.Label) error {
+	c.skip(ctx, "update label", append([]slog.Attr{slog.String("repo", orgRepo), slog.String("original", originalName)}, labelAttrs(label)...)...)
+	return nil
+}
+
+func (c *Client) DeleteLabel(ctx context.Context, orgRepo string, name string) error {
+	c.skip(ctx, "delete label", slog.String("repo", orgRepo), slog.String("label", name))
+	return nil
+}
+
+func (c *Client) CreateLabel(ctx context.Context, orgRepo string, label *api.Label) error {
+	c.skip(ctx, "create label", append([]slog.Attr{slog.String("repo", orgRepo)}, labelAttrs(label)...)...)
+	return nil
+}
+
+func milestoneAttrs(milestone *api.Milestone) []slog.Attr {
+	m := (*github.Milestone)(milestone)
+	attrs := []slog.Attr{slog.String("milestone", m.GetTitle()), slog.String("state", m.GetState()), slog.String("description", m.GetDescription())}
+	if m.DueOn != nil {
+		attrs = append(attrs, slog.Time("due", m.DueOn.Time))
+	}
+	return attrs
+}
+
+func (c *Client) UpdateMilestone(ctx context.Context, orgRepo string, number int, milestone *api.Milestone) error {
+	c.skip(ctx, "update milestone", append([]slog.Attr{slog.String("repo", orgRepo), slog.Int("number", number)}, milestoneAttrs(milestone)...)...)
+	return nil
+}
+
+func (c *Client) DeleteMilestone(ctx context.Context, orgRepo string, number int) error {
+	c.skip(ctx, "delete milestone", slog.String("repo", orgRepo), slog.Int("number", number))
+	return nil
+}
+
+func (c *Client) CreateMilestone(ctx context.Context, orgRepo string, milestone *api.Milestone) error {
+	c.skip(ctx, "create milestone", append([]slog.Attr{slog.String("repo", orgRepo)}, milestoneAttrs(milestone)...)...)
+	return nil
+}
+
+func (c *Client) UpdateRepository(ctx context.Context, orgRepo string, repository *api.Repository) error {
+	c.skip(ctx, "update repository", slog.String("repo", orgRepo))
+	return nil
+}
+
+func (c *Client) CreateRuleset(ctx context.Context, orgRepo string, ruleset *api.Ruleset) error {
+	c.skip(ctx, "create ruleset", slog.String("repo", orgRepo), slog.String("ruleset", ruleset.Name))
+	return nil
+}
+
+func (c *Client) UpdateRuleset(ctx context.Context, orgRepo string, id int64, ruleset *api.Ruleset) error {
+	c.skip(ctx, "update ruleset", slog.String("repo", orgRepo), slog.Int64("id", id), slog.String("ruleset", ruleset.Name))
+	return nil
+}
+
+func (c *Client) DeleteRuleset(ctx context.Context, orgRepo string, id int64) error {
+	c.skip(ctx, "delete ruleset", slog.String("repo", orgRepo), slog.Int64("id", id))
+	return nil
+}
+
+func (c *Client) SetTeamAccess(ctx context.Context, orgRepo string, team string, permission string) error {
+	c.skip(ctx, "set team access", slog.String("repo", orgRepo), slog.String("team", team), slog.String("permission", permission))
+	return nil
+}
+
+func (c *Client) RemoveTeamAccess(ctx context.Context, orgRepo string, team string) error {
+	c.skip(ctx, "remove team access", slog.String("repo", orgRepo), slog.String("team", team))
+	return nil
+}
+
+func (c *Client) SetCollaboratorAccess(ctx context.Context, orgRepo string, login string, permission string) error {
+	c.skip(ctx, "set collaborator access", slog.String("repo", orgRepo), slog.String("collaborator", login), slog.String("permission", permission))
+	return nil
+}
+
+func (c *Client) RemoveCollaboratorAccess(ctx context.Context, orgRepo string, login string) error {
+	c.skip(ctx, "remove collaborator access", slog.String("repo", orgRepo), slog.String("collaborator", login))
+	return nil
+}
+
+func (c *Client) ProposeFiles(ctx context.Context, orgRepo string, proposal *api.FileProposal) error {
+	paths := make([]string, 0, len(proposal.Files))
+	for _, f := range proposal.Files {
+		paths = append(paths, f.Path)
+	}
+	c.skip(ctx, "propose files", slog.String("repo", orgRepo), slog.String("branch", proposal.Branch), slog.String("files", strings.Join(paths, ",")))
+	return nil
+}
@@ -0,0 +1,73 @@
+package dryrun
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.New(fake.Fixture{Repos: map[string]fake.RepoFixture{
+		"org/repo": {
+			Labels:     []fake.LabelFixture{{Name: "bug"}},
+			Milestones: []fake.MilestoneFixture{{Number: 1, Title: "v1", State: "open"}},
+			Issues:     []fake.IssueFixture{{Number: 1, Title: "issue", State: "open", Labels: []string{"bug"}}},
+		},
+	}})
+	client := New(backend)
+
+	mutations := []struct {
+		op  string
+		run func() error
+	}{
+		{"update labels", func() error { return client.UpdateLabels(ctx, "org/repo", 1, nil) }},
+		{"update issue state", func() error { return client.UpdateIssueState(ctx, "org/repo", 1, "closed") }},
+		{"comment", func() error { return client.Comment(ctx, "org/repo", 1, "hello") }},
+		{"create label", func() error { return client.CreateLabel(ctx, "org/repo", &api.Label{Name: github.String("new")}) }},
+		{"update label", func() error {
+			return client.UpdateLabel(ctx, "org/repo", "bug", &api.Label{Name: github.String("renamed")})
+		}},
+		{"delete label", func() error { return client.DeleteLabel(ctx, "org/repo", "bug") }},
+		{"delete milestone", func() error { return client.DeleteMilestone(ctx, "org/repo", 1) }},
+		{"set team access", func() error { return client.SetTeamAccess(ctx, "org/repo", "platform", "write") }},
+		{"propose files", func() error {
+			return client.ProposeFiles(ctx, "org/repo", &api.FileProposal{Branch: "b", Files: []api.File{{Path: "README.md"}}})
+		}},
+	}
+	for _, m := range mutations {
+		if err := m.run(); err != nil {
+			t.Fatalf("%s: %v", m.op, err)
+		}
+	}
+
+	counts := client.Counts()
+	for _, m := range mutations {
+		if counts[m.op] != 1 {
+			t.Errorf("%s skipped %d times, want 1", m.op, counts[m.op])
+		}
+	}
+	labels, err := client.ListLabels(ctx, "org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0].GetName() != "bug" {
+		t.Errorf("labels changed to %v", labels)
+	}
+	issue, err := backend.GetIssue(ctx, "org/repo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *issue.State != "open" || len(issue.Labels) != 1 {
+		t.Errorf("issue changed to %s with %d labels", *issue.State, len(issue.Labels))
+	}
+	if comments, _ := backend.Comments("org/repo", 1); len(comments) != 0 {
+		t.Errorf("got comments %q", comments)
+	}
+	if prs, _ := backend.PullRequests("org/repo"); len(prs) != 0 {
+		t.Errorf("got pull requests %v", prs)
+	}
+}
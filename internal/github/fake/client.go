@@ -0,0 +1,1004 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+var ErrNotFound = errors.New("not found")
+
+var ErrAlreadyExists = errors.New("already exists")
+
+const defaultLabelColor = "ededed"
+
+type repo struct {
+	info     *api.Repository
+	rulesets []*api.Ruleset
+	// teams, collaborators and invitations map team slugs and logins to their permission.
+	teams         map[string]string
+	collaborators map[string]string
+	invitations   map[string]string
+	// invites counts the invitations sent.
+	invites int
+	// branches maps branch names to the content of their files by path.
+	branches     map[string]map[string]string
+	pullRequests []PullRequest
+	labels       []*api.Label
+	milestones   []*api.Milestone
+	issues       []*issue
+}
+
+type issue struct {
+	api.Issue
+	comments []string
+}
+
+// Client is an in-memory implementation of api.Client, it mimics the behaviour of the GitHub API closely enough
+// to exercise the grooming commands without a network.
+type Client struct {
+	mu    sync.Mutex
+	repos map[string]*repo
+	// Now is used to set the update timestamps of the modified objects.
+	Now func() time.Time
+}
+
+var _ api.Client = &Client{}
+
+func New(fixture Fixture) *Client {
+	c := &Client{
+		repos: map[string]*repo{},
+		Now:   time.Now,
+	}
+	for name, rf := range fixture.Repos {
+		r := &repo{
+			info:          newRepository(name, rf),
+			teams:         map[string]string{},
+			collaborators: map[string]string{},
+			invitations:   map[string]string{},
+			branches:      map[string]map[string]string{},
+		}
+		r.branches[r.info.GetDefaultBranch()] = maps.Clone(rf.Files)
+		if r.branches[r.info.GetDefaultBranch()] == nil {
+			r.branches[r.info.GetDefaultBranch()] = map[string]string{}
+		}
+		for team, permission := range rf.Teams {
+			r.teams[strings.ToLower(team)] = permission
+		}
+		for login, permission := range rf.Collaborators {
+			r.collaborators[strings.ToLower(login)] = permission
+		}
+		for login, permission := range rf.Invitations {
+			r.invitations[strings.ToLower(login)] = permission
+		}
+		for _, l := range rf.Labels {
+			color := l.Color
+			if color == "" {
+				color = defaultLabelColor
+			}
+			r.labels = append(r.labels, newLabel(l.Name, color, l.Description))
+		}
+		for _, m := range rf.Milestones {
+			state := m.State
+			if state == "" {
+				state = "open"
+			}
+			milestone := &api.Milestone{
+				Number:      github.Int(m.Number),
+				Title:       github.String(m.Title),
+				State:       github.String(state),
+				Description: github.String(m.Description),
+			}
+			if m.DueOn != nil {
+				milestone.DueOn = &github.Timestamp{Time: *m.DueOn}
+			}
+			r.milestones = append(r.milestones, milestone)
+		}
+		for _, i := range rf.Issues {
+			state := i.State
+			if state == "" {
+				state = "open"
+			}
+			created := i.CreatedAt
+			if created.IsZero() {
+				created = i.UpdatedAt
+			}
+			is := &issue{
+				Issue: api.Issue{
+					Number:    github.Int(i.Number),
+					Title:     github.String(i.Title),
+					State:     github.String(state),
+					CreatedAt: &github.Timestamp{Time: created},
+					UpdatedAt: &github.Timestamp{Time: i.UpdatedAt},
+				},
+				comments: append([]string{}, i.Comments...),
+			}
+			if i.PullRequest {
+				is.PullRequestLinks = &github.PullRequestLinks{}
+			}
+			for _, a := range i.Assignees {
+				is.Assignees = append(is.Assignees, &github.User{Login: github.String(a)})
+			}
+			for _, l := range i.Labels {
+				is.Labels = append(is.Labels, (*github.Label)(r.labelOrDefault(l)))
+			}
+			r.issues = append(r.issues, is)
+		}
+		c.repos[name] = r
+	}
+	return c
+}
+
+// newRepository applies the settings of the fixture on top of the defaults of GitHub.
+func newRepository(name string, rf RepoFixture) *api.Repository {
+	_, short, _ := utils.OrgRepo(name)
+	orDefault := func(b *bool, def bool) *bool {
+		if b != nil {
+			return github.Bool(*b)
+		}
+		return github.Bool(def)
+	}
+	set := rf.Settings
+	defaultBranch := set.DefaultBranch
+	if defaultBranch == "" {
+		defaultBranch = "main"
+	}
+	return &api.Repository{
+		Name:                github.String(short),
+		FullName:            github.String(name),
+		Archived:            github.Bool(rf.Archived),
+		Fork:                github.Bool(rf.Fork),
+		Topics:              append([]string{}, rf.Topics...),
+		Description:         github.String(set.Description),
+		Homepage:            github.String(set.Homepage),
+		DefaultBranch:       github.String(defaultBranch),
+		HasIssues:           orDefault(set.HasIssues, true),
+		HasWiki:             orDefault(set.HasWiki, true),
+		HasProjects:         orDefault(set.HasProjects, true),
+		HasDiscussions:      orDefault(set.HasDiscussions, false),
+		AllowMergeCommit:    orDefault(set.AllowMergeCommit, true),
+		AllowSquashMerge:    orDefault(set.AllowSquashMerge, true),
+		AllowRebaseMerge:    orDefault(set.AllowRebaseMerge, true),
+		DeleteBranchOnMerge: orDefault(set.DeleteBranchOnMerge, false),
+	}
+}
+
+func newLabel(name, color, description string) *api.Label {
+	return &api.Label{
+		Name:        github.String(name),
+		Color:       github.String(color),
+		Description: github.String(description),
+	}
+}
+
+// labelOrDefault returns the label called name, GitHub creates missing labels when they're set on an issue.
+func (r *repo) labelOrDefault(name string) *api.Label {
+	if l := r.label(name); l != nil {
+		return l
+	}
+	l := newLabel(name, defaultLabelColor, "")
+	r.labels = append(r.labels, l)
+	return l
+}
+
+// label names are case-insensitive on GitHub.
+func (r *repo) label(name string) *api.Label {
+	for _, l := range r.labels {
+		if strings.EqualFold(*l.Name, name) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (r *repo) milestone(number int) *api.Milestone {
+	for _, m := range r.milestones {
+		if *m.Number == number {
+			return m
+		}
+	}
+	return nil
+}
+
+func (r *repo) issue(number int) *issue {
+	for _, i := range r.issues {
+		if *i.Number == number {
+			return i
+		}
+	}
+	return nil
+}
+
+func (c *Client) repo(orgRepo string) (*repo, error) {
+	r, ok := c.repos[orgRepo]
+	if !ok {
+		return nil, fmt.Errorf("repo %s: %w", orgRepo, ErrNotFound)
+	}
+	return r, nil
+}
+
+func (c *Client) issue(orgRepo string, number int) (*issue, error) {
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	i := r.issue(number)
+	if i == nil {
+		return nil, fmt.Errorf("issue %s#%d: %w", orgRepo, number, ErrNotFound)
+	}
+	return i, nil
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *Client) GetIssue(ctx context.Context, orgRepo string, number int) (*api.Issue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.issue(orgRepo, number)
+	if err != nil {
+		return nil, err
+	}
+	return copyIssue(i), nil
+}
+
+// GetIssues lists issues like the GitHub API does: most recently created first, pull requests included and
+// pages of api.IssuesPerPage items where page 0 and 1 both are the first page.
+func (c *Client) GetIssues(ctx context.Context, orgRepo string, options api.IssueListOptions, page int) ([]*api.Issue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	var matching []*issue
+	for _, i := range r.issues {
+		if matches(i, options) {
+			matching = append(matching, i)
+		}
+	}
+	sort.SliceStable(matching, func(a, b int) bool {
+		return matching[a].CreatedAt.After(matching[b].CreatedAt.Time)
+	})
+	return copyIssues(paginate(matching, page, api.IssuesPerPage)), nil
+}
+
+func matches(i *issue, options api.IssueListOptions) bool {
+	switch options.State {
+	case "all":
+	case "", "open":
+		if *i.State != "open" {
+			return false
+		}
+	default:
+		if *i.State != options.State {
+			return false
+		}
+	}
+	if !options.Since.IsZero() && i.UpdatedAt.Before(options.Since) {
+		return false
+	}
+	for _, l := range strings.Split(options.Labels, ",") {
+		if l == "" {
+			continue
+		}
+		found := false
+		for _, il := range i.Labels {
+			if strings.EqualFold(*il.Name, l) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func paginate[T any](items []T, page int, perPage int) []T {
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return nil
+	}
+	return items[start:min(start+perPage, len(items))]
+}
+
+func (c *Client) UpdateLabels(ctx context.Context, orgRepo string, number int, labels []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.issue(orgRepo, number)
+	if err != nil {
+		return err
+	}
+	r := c.repos[orgRepo]
+	i.Labels = nil
+	for _, l := range labels {
+		i.Labels = append(i.Labels, (*github.Label)(r.labelOrDefault(l)))
+	}
+	i.UpdatedAt = &github.Timestamp{Time: c.Now()}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: update labels", slog.String("repo", orgRepo), slog.Int("issue", number), slog.String("labels", strings.Join(labels, ",")))
+	return nil
+}
+
+func (c *Client) UpdateIssueState(ctx context.Context, orgRepo string, number int, state string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.issue(orgRepo, number)
+	if err != nil {
+		return err
+	}
+	i.State = github.String(state)
+	now := c.Now()
+	i.UpdatedAt = &github.Timestamp{Time: now}
+	if state == "closed" {
+		i.ClosedAt = &github.Timestamp{Time: now}
+	} else {
+		i.ClosedAt = nil
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: update issue state", slog.String("repo", orgRepo), slog.Int("issue", number), slog.String("state", state))
+	return nil
+}
+
+func (c *Client) Comment(ctx context.Context, orgRepo string, number int, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.issue(orgRepo, number)
+	if err != nil {
+		return err
+	}
+	i.comments = append(i.comments, message)
+	i.UpdatedAt = &github.Timestamp{Time: c.Now()}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: comment", slog.String("repo", orgRepo), slog.Int("issue", number), slog.String("message", message))
+	return nil
+}
+
+// Comments returns the comments posted on an issue, useful to assert on what a run did.
+func (c *Client) Comments(orgRepo string, number int) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.issue(orgRepo, number)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{}, i.comments...), nil
+}
+
+func (c *Client) ListLabels(ctx context.Context, orgRepo string) ([]*api.Label, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*api.Label, len(r.labels))
+	for i, l := range r.labels {
+		res[i] = copyLabel(l)
+	}
+	return res, nil
+}
+
+func (c *Client) UpdateLabel(ctx context.Context, orgRepo string, originalName string, label *api.Label) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	cur := r.label(originalName)
+	if cur == nil {
+		return fmt.Errorf("label %s %q: %w", orgRepo, originalName, ErrNotFound)
+	}
+	if label.Name != nil && !strings.EqualFold(*label.Name, originalName) && r.label(*label.Name) != nil {
+		return fmt.Errorf("label %s %q: %w", orgRepo, *label.Name, ErrAlreadyExists)
+	}
+	// Issues point to the same label so renames are visible on them too.
+	if label.Name != nil {
+		cur.Name = github.String(*label.Name)
+	}
+	if label.Color != nil {
+		cur.Color = github.String(*label.Color)
+	}
+	if label.Description != nil {
+		cur.Description = github.String(*label.Description)
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: update label", slog.String("repo", orgRepo), slog.String("label", originalName))
+	return nil
+}
+
+func (c *Client) DeleteLabel(ctx context.Context, orgRepo string, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	cur := r.label(name)
+	if cur == nil {
+		return fmt.Errorf("label %s %q: %w", orgRepo, name, ErrNotFound)
+	}
+	r.labels = remove(r.labels, cur)
+	for _, i := range r.issues {
+		i.Labels = remove(i.Labels, (*github.Label)(cur))
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: delete label", slog.String("repo", orgRepo), slog.String("label", name))
+	return nil
+}
+
+func (c *Client) CreateLabel(ctx context.Context, orgRepo string, label *api.Label) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if label.Name == nil || *label.Name == "" {
+		return errors.New("label name is required")
+	}
+	if r.label(*label.Name) != nil {
+		return fmt.Errorf("label %s %q: %w", orgRepo, *label.Name, ErrAlreadyExists)
+	}
+	gl := (*github.Label)(label)
+	color := gl.GetColor()
+	if color == "" {
+		color = defaultLabelColor
+	}
+	r.labels = append(r.labels, newLabel(*label.Name, color, gl.GetDescription()))
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: create label", slog.String("repo", orgRepo), slog.String("label", *label.Name))
+	return nil
+}
+
+func (c *Client) ListMilestones(ctx context.Context, orgRepo string) ([]*api.Milestone, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*api.Milestone, len(r.milestones))
+	for i, m := range r.milestones {
+		res[i] = copyMilestone(m)
+	}
+	return res, nil
+}
+
+func (c *Client) UpdateMilestone(ctx context.Context, orgRepo string, number int, milestone *api.Milestone) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	cur := r.milestone(number)
+	if cur == nil {
+		return fmt.Errorf("milestone %s #%d: %w", orgRepo, number, ErrNotFound)
+	}
+	if milestone.Title != nil {
+		cur.Title = github.String(*milestone.Title)
+	}
+	if milestone.State != nil {
+		cur.State = github.String(*milestone.State)
+	}
+	if milestone.Description != nil {
+		cur.Description = github.String(*milestone.Description)
+	}
+	if milestone.DueOn != nil {
+		cur.DueOn = &github.Timestamp{Time: milestone.DueOn.Time}
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: update milestone", slog.String("repo", orgRepo), slog.Int("milestone", number))
+	return nil
+}
+
+func (c *Client) DeleteMilestone(ctx context.Context, orgRepo string, number int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	cur := r.milestone(number)
+	if cur == nil {
+		return fmt.Errorf("milestone %s #%d: %w", orgRepo, number, ErrNotFound)
+	}
+	r.milestones = remove(r.milestones, cur)
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: delete milestone", slog.String("repo", orgRepo), slog.Int("milestone", number))
+	return nil
+}
+
+func (c *Client) CreateMilestone(ctx context.Context, orgRepo string, milestone *api.Milestone) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if milestone.Title == nil || *milestone.Title == "" {
+		return errors.New("milestone title is required")
+	}
+	number := 1
+	for _, m := range r.milestones {
+		if *m.Title == *milestone.Title {
+			return fmt.Errorf("milestone %s %q: %w", orgRepo, *milestone.Title, ErrAlreadyExists)
+		}
+		number = max(number, *m.Number+1)
+	}
+	m := copyMilestone(milestone)
+	m.Number = github.Int(number)
+	if m.State == nil {
+		m.State = github.String("open")
+	}
+	r.milestones = append(r.milestones, m)
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: create milestone", slog.String("repo", orgRepo), slog.String("milestone", *milestone.Title))
+	return nil
+}
+
+// ListRepositories lists the repositories of the fixture in org, sorted by name.
+func (c *Client) ListRepositories(ctx context.Context, org string) ([]*api.Repository, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []*api.Repository
+	for name, repo := range c.repos {
+		o, _, err := utils.OrgRepo(name)
+		if err != nil || !strings.EqualFold(o, org) {
+			continue
+		}
+		res = append(res, copyRepository(repo.info))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res, nil
+}
+
+func (c *Client) GetRepository(ctx context.Context, orgRepo string) (*api.Repository, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	return copyRepository(r.info), nil
+}
+
+func (c *Client) UpdateRepository(ctx context.Context, orgRepo string, repository *api.Repository) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if r.info.GetArchived() {
+		return fmt.Errorf("repo %s is archived and read-only", orgRepo)
+	}
+	info := r.info
+	setString := func(into **string, from *string) {
+		if from != nil {
+			*into = github.String(*from)
+		}
+	}
+	setBool := func(into **bool, from *bool) {
+		if from != nil {
+			*into = github.Bool(*from)
+		}
+	}
+	setString(&info.Description, repository.Description)
+	setString(&info.Homepage, repository.Homepage)
+	setString(&info.DefaultBranch, repository.DefaultBranch)
+	setBool(&info.HasIssues, repository.HasIssues)
+	setBool(&info.HasWiki, repository.HasWiki)
+	setBool(&info.HasProjects, repository.HasProjects)
+	setBool(&info.HasDiscussions, repository.HasDiscussions)
+	setBool(&info.AllowMergeCommit, repository.AllowMergeCommit)
+	setBool(&info.AllowSquashMerge, repository.AllowSquashMerge)
+	setBool(&info.AllowRebaseMerge, repository.AllowRebaseMerge)
+	setBool(&info.DeleteBranchOnMerge, repository.DeleteBranchOnMerge)
+	if repository.Topics != nil {
+		info.Topics = append([]string{}, repository.Topics...)
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: update repository", slog.String("repo", orgRepo))
+	return nil
+}
+
+func remove[T comparable](items []T, item T) []T {
+	var res []T
+	for _, i := range items {
+		if i != item {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+func (c *Client) ListRulesets(ctx context.Context, orgRepo string) ([]*api.Ruleset, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*api.Ruleset, len(r.rulesets))
+	for i, rs := range r.rulesets {
+		res[i] = copyRuleset(rs)
+	}
+	return res, nil
+}
+
+func (r *repo) ruleset(id int64) *api.Ruleset {
+	for _, rs := range r.rulesets {
+		if rs.GetID() == id {
+			return rs
+		}
+	}
+	return nil
+}
+
+func (c *Client) CreateRuleset(ctx context.Context, orgRepo string, ruleset *api.Ruleset) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if ruleset.Name == "" {
+		return errors.New("ruleset name is required")
+	}
+	id := int64(1)
+	for _, rs := range r.rulesets {
+		if rs.Name == ruleset.Name {
+			return fmt.Errorf("ruleset %s %q: %w", orgRepo, ruleset.Name, ErrAlreadyExists)
+		}
+		id = max(id, rs.GetID()+1)
+	}
+	rs := copyRuleset(ruleset)
+	rs.ID = github.Int64(id)
+	r.rulesets = append(r.rulesets, rs)
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: create ruleset", slog.String("repo", orgRepo), slog.String("ruleset", ruleset.Name))
+	return nil
+}
+
+func (c *Client) UpdateRuleset(ctx context.Context, orgRepo string, id int64, ruleset *api.Ruleset) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	cur := r.ruleset(id)
+	if cur == nil {
+		return fmt.Errorf("ruleset %s #%d: %w", orgRepo, id, ErrNotFound)
+	}
+	updated := copyRuleset(ruleset)
+	updated.ID = github.Int64(id)
+	*cur = *updated
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: update ruleset", slog.String("repo", orgRepo), slog.String("ruleset", ruleset.Name))
+	return nil
+}
+
+func (c *Client) DeleteRuleset(ctx context.Context, orgRepo string, id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	cur := r.ruleset(id)
+	if cur == nil {
+		return fmt.Errorf("ruleset %s #%d: %w", orgRepo, id, ErrNotFound)
+	}
+	r.rulesets = remove(r.rulesets, cur)
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: delete ruleset", slog.String("repo", orgRepo), slog.Int64("ruleset", id))
+	return nil
+}
+
+func listAccess(grants map[string]string) []*api.Access {
+	res := make([]*api.Access, 0, len(grants))
+	for name, permission := range grants {
+		res = append(res, &api.Access{Name: name, Permission: permission})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func validPermission(permission string) error {
+	if !slices.Contains(api.Permissions, permission) {
+		return fmt.Errorf("invalid permission %q", permission)
+	}
+	return nil
+}
+
+func (c *Client) ListTeamAccess(ctx context.Context, orgRepo string) ([]*api.Access, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	return listAccess(r.teams), nil
+}
+
+func (c *Client) SetTeamAccess(ctx context.Context, orgRepo string, team string, permission string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if err := validPermission(permission); err != nil {
+		return err
+	}
+	r.teams[strings.ToLower(team)] = permission
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: set team access", slog.String("repo", orgRepo), slog.String("team", team), slog.String("permission", permission))
+	return nil
+}
+
+func (c *Client) RemoveTeamAccess(ctx context.Context, orgRepo string, team string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.teams[strings.ToLower(team)]; !ok {
+		return fmt.Errorf("team %s of %s: %w", team, orgRepo, ErrNotFound)
+	}
+	delete(r.teams, strings.ToLower(team))
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: remove team access", slog.String("repo", orgRepo), slog.String("team", team))
+	return nil
+}
+
+func (c *Client) ListCollaboratorAccess(ctx context.Context, orgRepo string) ([]*api.Access, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	res := listAccess(r.collaborators)
+	for _, a := range listAccess(r.invitations) {
+		a.Pending = true
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+// SetCollaboratorAccess invites the users who aren't collaborators yet like GitHub, invitations are never accepted.
+func (c *Client) SetCollaboratorAccess(ctx context.Context, orgRepo string, login string, permission string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	if err := validPermission(permission); err != nil {
+		return err
+	}
+	key := strings.ToLower(login)
+	if _, ok := r.collaborators[key]; ok {
+		r.collaborators[key] = permission
+		slog.LogAttrs(ctx, slog.LevelInfo, "fake: set collaborator access", slog.String("repo", orgRepo), slog.String("collaborator", login), slog.String("permission", permission))
+		return nil
+	}
+	if _, ok := r.invitations[key]; !ok {
+		r.invites += 1
+	}
+	r.invitations[key] = permission
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: invite collaborator", slog.String("repo", orgRepo), slog.String("collaborator", login), slog.String("permission", permission))
+	return nil
+}
+
+// Invites returns how many invitations were sent to become a collaborator of orgRepo.
+func (c *Client) Invites(orgRepo string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return 0
+	}
+	return r.invites
+}
+
+func (c *Client) RemoveCollaboratorAccess(ctx context.Context, orgRepo string, login string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	key := strings.ToLower(login)
+	if _, ok := r.invitations[key]; ok {
+		delete(r.invitations, key)
+		slog.LogAttrs(ctx, slog.LevelInfo, "fake: cancel invitation", slog.String("repo", orgRepo), slog.String("collaborator", login))
+		return nil
+	}
+	if _, ok := r.collaborators[key]; !ok {
+		return fmt.Errorf("collaborator %s of %s: %w", login, orgRepo, ErrNotFound)
+	}
+	delete(r.collaborators, key)
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: remove collaborator access", slog.String("repo", orgRepo), slog.String("collaborator", login))
+	return nil
+}
+
+// PullRequest is a pull request opened by ProposeFiles.
+type PullRequest struct {
+	Branch string
+	Title  string
+	Body   string
+	// State is open, closed or merged.
+	State string
+}
+
+func (c *Client) GetFile(ctx context.Context, orgRepo string, path string, ref string) (*api.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		ref = r.info.GetDefaultBranch()
+	}
+	files, ok := r.branches[ref]
+	if !ok {
+		return nil, fmt.Errorf("branch %s of %s: %w", ref, orgRepo, ErrNotFound)
+	}
+	content, ok := files[path]
+	if !ok {
+		return nil, nil
+	}
+	return &api.File{Path: path, Content: []byte(content)}, nil
+}
+
+// ProposeFiles follows the policy of the GitHub client: the branch of an open pull request is updated, otherwise
+// it's reset to the default branch and a new pull request is opened, unless the last one was closed without
+// merging and the branch still holds the same files.
+func (c *Client) ProposeFiles(ctx context.Context, orgRepo string, proposal *api.FileProposal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	var latest *PullRequest
+	for i := range r.pullRequests {
+		if r.pullRequests[i].Branch == proposal.Branch {
+			latest = &r.pullRequests[i]
+		}
+	}
+	open := latest != nil && latest.State == "open"
+	branch, exists := r.branches[proposal.Branch]
+	if !open {
+		if exists && latest != nil && latest.State == "closed" && sameFiles(branch, proposal.Files) {
+			slog.LogAttrs(ctx, slog.LevelInfo, "fake: skip declined proposal", slog.String("repo", orgRepo), slog.String("branch", proposal.Branch))
+			return nil
+		}
+		branch = maps.Clone(r.branches[r.info.GetDefaultBranch()])
+		r.branches[proposal.Branch] = branch
+	}
+	for _, f := range proposal.Files {
+		branch[f.Path] = string(f.Content)
+	}
+	if open {
+		return nil
+	}
+	r.pullRequests = append(r.pullRequests, PullRequest{Branch: proposal.Branch, Title: proposal.Title, Body: proposal.Body, State: "open"})
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake: open pull request", slog.String("repo", orgRepo), slog.String("branch", proposal.Branch))
+	return nil
+}
+
+func sameFiles(branch map[string]string, files []api.File) bool {
+	for _, f := range files {
+		if content, ok := branch[f.Path]; !ok || content != string(f.Content) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClosePullRequest closes the open pull request from branch, merging it into the default branch if merge is set.
+func (c *Client) ClosePullRequest(orgRepo string, branch string, merge bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	for i, pr := range r.pullRequests {
+		if pr.Branch != branch || pr.State != "open" {
+			continue
+		}
+		r.pullRequests[i].State = "closed"
+		if merge {
+			r.pullRequests[i].State = "merged"
+			maps.Copy(r.branches[r.info.GetDefaultBranch()], r.branches[branch])
+		}
+		return nil
+	}
+	return fmt.Errorf("open pull request from %s of %s: %w", branch, orgRepo, ErrNotFound)
+}
+
+// SetFile commits a file to a branch, to simulate changes made outside of meta-sync.
+func (c *Client) SetFile(orgRepo string, branch string, path string, content string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return err
+	}
+	files, ok := r.branches[branch]
+	if !ok {
+		return fmt.Errorf("branch %s of %s: %w", branch, orgRepo, ErrNotFound)
+	}
+	files[path] = content
+	return nil
+}
+
+// PullRequests returns the pull requests opened on a repository, useful to assert on what a run did.
+func (c *Client) PullRequests(orgRepo string) ([]PullRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, err := c.repo(orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	return append([]PullRequest{}, r.pullRequests...), nil
+}
+
+// copyRuleset goes through JSON as the rules hold raw parameters.
+func copyRuleset(rs *api.Ruleset) *api.Ruleset {
+	b, err := json.Marshal(rs)
+	if err != nil {
+		panic(err)
+	}
+	res := &api.Ruleset{}
+	if err := json.Unmarshal(b, res); err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func copyRepository(r *api.Repository) *api.Repository {
+	cp := *r
+	cp.Topics = append([]string{}, r.Topics...)
+	return &cp
+}
+
+func copyLabel(l *api.Label) *api.Label {
+	gl := (*github.Label)(l)
+	return newLabel(gl.GetName(), gl.GetColor(), gl.GetDescription())
+}
+
+func copyMilestone(m *api.Milestone) *api.Milestone {
+	res := *m
+	return &res
+}
+
+func copyIssue(i *issue) *api.Issue {
+	res := i.Issue
+	res.Labels = nil
+	for _, l := range i.Labels {
+		res.Labels = append(res.Labels, (*github.Label)(copyLabel((*api.Label)(l))))
+	}
+	res.Comments = github.Int(len(i.comments))
+	return &res
+}
+
+func copyIssues(issues []*issue) []*api.Issue {
+	res := make([]*api.Issue, len(issues))
+	for i := range issues {
+		res[i] = copyIssue(issues[i])
+	}
+	return res
+}
@@ -0,0 +1,86 @@
+package fake
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is the seed data of a fake GitHub. It can be written as YAML or JSON.
+type Fixture struct {
+	Repos map[string]RepoFixture `yaml:"repos" json:"repos"`
+}
+
+type RepoFixture struct {
+	Archived bool            `yaml:"archived" json:"archived"`
+	Fork     bool            `yaml:"fork" json:"fork"`
+	Topics   []string        `yaml:"topics" json:"topics"`
+	Settings SettingsFixture `yaml:"settings" json:"settings"`
+	// Teams and Collaborators map team slugs and the logins granted access to the repository itself to their
+	// permission.
+	Teams         map[string]string `yaml:"teams" json:"teams"`
+	Collaborators map[string]string `yaml:"collaborators" json:"collaborators"`
+	// Invitations map the logins invited to become a collaborator to their permission.
+	Invitations map[string]string `yaml:"invitations" json:"invitations"`
+	// Files maps paths to their content on the default branch.
+	Files      map[string]string  `yaml:"files" json:"files"`
+	Labels     []LabelFixture     `yaml:"labels" json:"labels"`
+	Milestones []MilestoneFixture `yaml:"milestones" json:"milestones"`
+	Issues     []IssueFixture     `yaml:"issues" json:"issues"`
+}
+
+// SettingsFixture overrides the defaults of a new GitHub repository.
+type SettingsFixture struct {
+	Description         string `yaml:"description" json:"description"`
+	Homepage            string `yaml:"homepage" json:"homepage"`
+	DefaultBranch       string `yaml:"defaultBranch" json:"defaultBranch"`
+	HasIssues           *bool  `yaml:"hasIssues" json:"hasIssues"`
+	HasWiki             *bool  `yaml:"hasWiki" json:"hasWiki"`
+	HasProjects         *bool  `yaml:"hasProjects" json:"hasProjects"`
+	HasDiscussions      *bool  `yaml:"hasDiscussions" json:"hasDiscussions"`
+	AllowMergeCommit    *bool  `yaml:"allowMergeCommit" json:"allowMergeCommit"`
+	AllowSquashMerge    *bool  `yaml:"allowSquashMerge" json:"allowSquashMerge"`
+	AllowRebaseMerge    *bool  `yaml:"allowRebaseMerge" json:"allowRebaseMerge"`
+	DeleteBranchOnMerge *bool  `yaml:"deleteBranchOnMerge" json:"deleteBranchOnMerge"`
+}
+
+type LabelFixture struct {
+	Name        string `yaml:"name" json:"name"`
+	Color       string `yaml:"color" json:"color"`
+	Description string `yaml:"description" json:"description"`
+}
+
+type MilestoneFixture struct {
+	Number      int        `yaml:"number" json:"number"`
+	Title       string     `yaml:"title" json:"title"`
+	State       string     `yaml:"state" json:"state"`
+	Description string     `yaml:"description" json:"description"`
+	DueOn       *time.Time `yaml:"dueOn" json:"dueOn"`
+}
+
+type IssueFixture struct {
+	Number      int       `yaml:"number" json:"number"`
+	Title       string    `yaml:"title" json:"title"`
+	State       string    `yaml:"state" json:"state"`
+	Labels      []string  `yaml:"labels" json:"labels"`
+	Assignees   []string  `yaml:"assignees" json:"assignees"`
+	Comments    []string  `yaml:"comments" json:"comments"`
+	PullRequest bool      `yaml:"pullRequest" json:"pullRequest"`
+	CreatedAt   time.Time `yaml:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time `yaml:"updatedAt" json:"updatedAt"`
+}
+
+// LoadFixture reads a fixture file, JSON being valid YAML both formats are accepted.
+func LoadFixture(path string) (Fixture, error) {
+	out := Fixture{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return out, err
+	}
+	if err := yaml.Unmarshal(b, &out); err != nil {
+		return out, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return out, nil
+}
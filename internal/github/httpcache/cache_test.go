@@ -0,0 +1,74 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		body   string
+		// wantRevalidated is whether the second request is conditional.
+		wantRevalidated bool
+	}{
+		{name: "etag", method: http.MethodGet, header: http.Header{"Etag": {`"v1"`}}, wantRevalidated: true},
+		{name: "last modified", method: http.MethodGet, header: http.Header{"Last-Modified": {"Mon, 01 Jan 2024 00:00:00 GMT"}}, wantRevalidated: true},
+		{name: "no validator", method: http.MethodGet, header: http.Header{}},
+		{name: "not a get", method: http.MethodPost, header: http.Header{"Etag": {`"v1"`}}, body: "{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conditional []bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("X-Ratelimit-Remaining", "4999")
+				revalidating := r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+				conditional = append(conditional, revalidating)
+				if revalidating {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = io.WriteString(w, "payload")
+			}))
+			defer srv.Close()
+
+			transport, err := New(t.TempDir(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: transport}
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(tt.method, srv.URL+"/repos/org/repo/labels", strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != http.StatusOK || string(b) != "payload" {
+					t.Errorf("request %d got %d %q, want 200 \"payload\"", i, resp.StatusCode, b)
+				}
+				if resp.Header.Get("X-Ratelimit-Remaining") != "4999" {
+					t.Errorf("request %d lost the fresh headers", i)
+				}
+			}
+			if conditional[0] || conditional[1] != tt.wantRevalidated {
+				t.Errorf("conditional requests are %v, want [false %v]", conditional, tt.wantRevalidated)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ type Selector struct {
 	Since     time.Duration
 	Limit     int
 	IssueList string
+	// IncludePullRequests selects pull requests even with the GraphQL API.
+	IncludePullRequests bool
 }
 
 func (l Selector) Validate() error {
@@ -28,8 +30,9 @@ func (l Selector) Validate() error {
 
 func (l Selector) listOpts(now time.Time) api.IssueListOptions {
 	r := api.IssueListOptions{
-		State:  l.State,
-		Labels: l.Labels,
+		State:               l.State,
+		Labels:              l.Labels,
+		IncludePullRequests: l.IncludePullRequests,
 	}
 	if l.Since != 0 {
 		r.Since = now.Add(-l.Since)
@@ -61,7 +64,7 @@ func (l Selector) Iterator(ctx context.Context, client api.Client, now time.Time
 		})
 	} else {
 		opts := l.listOpts(now)
-		page := 0
+		page := 1
 		var currentItems []*api.Issue
 		left := l.Limit
 		if left == 0 {
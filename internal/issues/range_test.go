@@ -0,0 +1,57 @@
+package issues
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+)
+
+func TestSelectorIterator(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		issues int
+		want   int
+	}{
+		{name: "no issues", issues: 0, want: 0},
+		{name: "one page", issues: 30, want: 30},
+		{name: "full page", issues: 100, want: 100},
+		{name: "several pages", issues: 250, want: 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rf := fake.RepoFixture{}
+			for i := 1; i <= tt.issues; i++ {
+				rf.Issues = append(rf.Issues, fake.IssueFixture{
+					Number:    i,
+					Title:     "issue " + strconv.Itoa(i),
+					State:     "open",
+					CreatedAt: now.Add(-time.Duration(i) * time.Hour),
+				})
+			}
+			client := fake.New(fake.Fixture{Repos: map[string]fake.RepoFixture{"org/repo": rf}})
+
+			it := Selector{Repo: "org/repo", State: "open"}.Iterator(context.Background(), client, now)
+			seen := map[int]bool{}
+			for {
+				issue, err := it.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if issue == nil {
+					break
+				}
+				if seen[*issue.Number] {
+					t.Fatalf("issue %d listed twice", *issue.Number)
+				}
+				seen[*issue.Number] = true
+			}
+			if len(seen) != tt.want {
+				t.Errorf("got %d issues, want %d", len(seen), tt.want)
+			}
+		})
+	}
+}
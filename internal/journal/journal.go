@@ -0,0 +1,131 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/mutation"
+)
+
+// Entry is a line of a journal.
+type Entry struct {
+	Time time.Time `json:"time"`
+	mutation.Op
+}
+
+// Client wraps an api.Client and appends every mutation, with the state it replaced, to the journal of the run.
+type Client struct {
+	*mutation.Client
+	RunID string
+
+	client api.Client
+	path   string
+	mu     sync.Mutex
+	out    *os.File
+}
+
+var _ api.Client = &Client{}
+
+func NewRunID(now time.Time) string {
+	return now.UTC().Format("20060102T150405.000Z")
+}
+
+func Path(dir string, runID string) string {
+	return filepath.Join(dir, runID+".jsonl")
+}
+
+func New(client api.Client, dir string, runID string) (*Client, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	c := &Client{RunID: runID, client: client, path: Path(dir, runID)}
+	c.Client = mutation.NewClient(client, c.apply)
+	return c, nil
+}
+
+// Read returns the operations of a run in the order they were made.
+func Read(dir string, runID string) ([]mutation.Op, error) {
+	f, err := os.Open(Path(dir, runID))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []mutation.Op
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid journal %s:%d: %w", f.Name(), line, err)
+		}
+		ops = append(ops, e.Op)
+	}
+	return ops, scanner.Err()
+}
+
+func (c *Client) record(ctx context.Context, op mutation.Op) error {
+	b, err := json.Marshal(Entry{Time: time.Now(), Op: op})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Created on the first mutation so read-only runs leave nothing behind.
+	if c.out == nil {
+		c.out, err = os.OpenFile(c.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		slog.LogAttrs(ctx, slog.LevelInfo, "journaling mutations", slog.String("run", c.RunID), slog.String("path", c.path))
+	}
+	_, err = c.out.Write(append(b, '\n'))
+	return err
+}
+
+func (c *Client) apply(ctx context.Context, op mutation.Op) error {
+	if err := mutation.Apply(ctx, c.client, op); err != nil {
+		return err
+	}
+	if err := mutation.LookupCreated(ctx, c.client, &op); err != nil {
+		return err
+	}
+	return c.record(ctx, op)
+}
+
+// Undo reverts the operations of a run, last one first. Operations which can't be reverted are logged and skipped,
+// so are the ones about objects changed since the run which are reported once everything else is undone.
+func Undo(ctx context.Context, client api.Client, ops []mutation.Op) error {
+	conflicts := 0
+	for i := len(ops) - 1; i >= 0; i-- {
+		inv, ok := mutation.Inverse(ops[i])
+		if !ok {
+			slog.LogAttrs(ctx, slog.LevelWarn, "can't undo operation, skipping", slog.String("op", ops[i].String()))
+			continue
+		}
+		if err := mutation.VerifyApplied(ctx, client, ops[i]); err != nil {
+			if !mutation.IsChanged(err) {
+				return err
+			}
+			slog.LogAttrs(ctx, slog.LevelWarn, "can't undo operation, skipping", slog.String("op", ops[i].String()), slog.String("error", err.Error()))
+			conflicts += 1
+			continue
+		}
+		slog.LogAttrs(ctx, slog.LevelInfo, "undoing operation", slog.String("op", ops[i].String()))
+		if err := mutation.Apply(ctx, client, inv); err != nil {
+			return fmt.Errorf("failed to undo %s: %w", ops[i], err)
+		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d operations weren't undone as their objects changed since the run", conflicts)
+	}
+	return nil
+}
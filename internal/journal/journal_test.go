@@ -0,0 +1,172 @@
+package journal
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+)
+
+var fixture = fake.Fixture{Repos: map[string]fake.RepoFixture{
+	"org/repo": {
+		Labels:     []fake.LabelFixture{{Name: "bug", Color: "ff0000"}, {Name: "old", Color: "000000"}, {Name: "wontfix"}},
+		Milestones: []fake.MilestoneFixture{{Number: 1, Title: "v1", State: "open"}},
+		Issues:     []fake.IssueFixture{{Number: 1, Title: "issue", State: "open", Labels: []string{"bug"}}},
+		Teams:      map[string]string{"platform": "read"},
+	},
+}}
+
+// snapshot describes what the operations of the tests change.
+func snapshot(t *testing.T, client api.Client) []string {
+	t.Helper()
+	ctx := context.Background()
+	var res []string
+	labels, err := client.ListLabels(ctx, "org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range labels {
+		res = append(res, "label "+l.GetName()+" "+l.GetColor())
+	}
+	milestones, err := client.ListMilestones(ctx, "org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range milestones {
+		res = append(res, "milestone "+m.GetTitle()+" "+m.GetState())
+	}
+	issue, err := client.GetIssue(ctx, "org/repo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = append(res, "issue "+*issue.State)
+	for _, l := range issue.Labels {
+		res = append(res, "issue label "+l.GetName())
+	}
+	teams, err := client.ListTeamAccess(ctx, "org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range teams {
+		res = append(res, "team "+a.Name+" "+a.Permission)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// mutate makes one change of each kind through client.
+func mutate(t *testing.T, client api.Client) {
+	t.Helper()
+	ctx := context.Background()
+	steps := []func() error{
+		func() error { return client.UpdateLabels(ctx, "org/repo", 1, []string{"bug", "wontfix"}) },
+		func() error { return client.UpdateIssueState(ctx, "org/repo", 1, "closed") },
+		func() error {
+			return client.UpdateLabel(ctx, "org/repo", "bug", &api.Label{Color: github.String("00ff00")})
+		},
+		func() error {
+			return client.CreateLabel(ctx, "org/repo", &api.Label{Name: github.String("new"), Color: github.String("0000ff")})
+		},
+		func() error { return client.DeleteLabel(ctx, "org/repo", "old") },
+		func() error {
+			return client.CreateMilestone(ctx, "org/repo", &api.Milestone{Title: github.String("v2")})
+		},
+		func() error {
+			return client.UpdateMilestone(ctx, "org/repo", 1, &api.Milestone{State: github.String("closed")})
+		},
+		func() error { return client.SetTeamAccess(ctx, "org/repo", "platform", "write") },
+		func() error { return client.SetTeamAccess(ctx, "org/repo", "security", "read") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name string
+		// external changes objects after the run.
+		external func(ctx context.Context, client api.Client) error
+		// wantKept are the changes of the run which aren't undone.
+		wantKept []string
+		wantErr  bool
+	}{
+		{
+			name: "everything undone",
+		},
+		{
+			name: "label changed since",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.UpdateLabel(ctx, "org/repo", "bug", &api.Label{Color: github.String("123456")})
+			},
+			wantKept: []string{"label bug 123456"},
+			wantErr:  true,
+		},
+		{
+			name: "created label deleted since",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.DeleteLabel(ctx, "org/repo", "new")
+			},
+			wantErr: true,
+		},
+		{
+			name: "issue labels changed since",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.UpdateLabels(ctx, "org/repo", 1, nil)
+			},
+			wantKept: []string{"-issue label bug"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := fake.New(fixture)
+			want := snapshot(t, backend)
+
+			dir := t.TempDir()
+			client, err := New(backend, dir, NewRunID(time.Now()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mutate(t, client)
+			if tt.external != nil {
+				if err := tt.external(ctx, backend); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ops, err := Read(dir, client.RunID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ops) != 9 {
+				t.Fatalf("journaled %d operations, want 9", len(ops))
+			}
+			err = Undo(ctx, backend, ops)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one: %v", err, tt.wantErr)
+			}
+
+			for _, kept := range tt.wantKept {
+				if kept[0] == '-' {
+					want = slices.DeleteFunc(want, func(s string) bool { return s == kept[1:] })
+					continue
+				}
+				want = append(want, kept)
+				// The undone color doesn't come back.
+				want = slices.DeleteFunc(want, func(s string) bool { return s == "label bug ff0000" })
+			}
+			slices.Sort(want)
+			if got := snapshot(t, backend); !slices.Equal(got, want) {
+				t.Errorf("got %q after undo, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package labels
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+	"github.com/pmalek/github-pm-groomer/internal/issues"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	fixture := fake.Fixture{Repos: map[string]fake.RepoFixture{
+		"org/repo": {
+			Labels: []fake.LabelFixture{{Name: "bug"}, {Name: "kind/bug"}, {Name: "triage"}},
+			Issues: []fake.IssueFixture{
+				{Number: 1, Title: "one", State: "open", Labels: []string{"bug"}, CreatedAt: now.Add(-time.Hour)},
+				{Number: 2, Title: "two", State: "open", Labels: []string{"bug", "triage"}, CreatedAt: now.Add(-2 * time.Hour)},
+				{Number: 3, Title: "three", State: "open", CreatedAt: now.Add(-3 * time.Hour)},
+			},
+		},
+	}}
+	tests := []struct {
+		name string
+		opts Opts
+		want map[int][]string
+	}{
+		{
+			name: "add",
+			opts: Opts{Action: AddAction, Label: "triage"},
+			want: map[int][]string{1: {"bug", "triage"}, 2: {"bug", "triage"}, 3: {"triage"}},
+		},
+		{
+			name: "remove",
+			opts: Opts{Action: RemoveAction, Label: "bug"},
+			want: map[int][]string{1: nil, 2: {"triage"}, 3: nil},
+		},
+		{
+			name: "replace",
+			opts: Opts{Action: ReplaceAction, Label: "bug", NewLabel: "kind/bug"},
+			want: map[int][]string{1: {"kind/bug"}, 2: {"kind/bug", "triage"}, 3: nil},
+		},
+		{
+			name: "remove with a selector",
+			opts: Opts{Action: RemoveAction, Label: "bug", IssueSelector: issues.Selector{Labels: "triage"}},
+			want: map[int][]string{1: {"bug"}, 2: {"triage"}, 3: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.New(fixture)
+			tt.opts.IssueSelector.Repo = "org/repo"
+			tt.opts.IssueSelector.State = "open"
+			if err := tt.opts.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if err := Run(context.Background(), client, tt.opts, now); err != nil {
+				t.Fatal(err)
+			}
+			for number, want := range tt.want {
+				issue, err := client.GetIssue(context.Background(), "org/repo", number)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, l := range issue.Labels {
+					got = append(got, l.GetName())
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, want) {
+					t.Errorf("issue %d has labels %v, want %v", number, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package lifecycle
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+	"github.com/pmalek/github-pm-groomer/internal/issues"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		labels       []string
+		updated      time.Duration
+		wantLabels   []string
+		wantState    string
+		wantComments int
+	}{
+		{name: "active", updated: 24 * time.Hour, wantState: "open"},
+		{name: "inactive becomes stale", updated: 40 * 24 * time.Hour, wantLabels: []string{"stale"}, wantState: "open", wantComments: 1},
+		{name: "stale stays stale", labels: []string{"stale"}, updated: 40 * 24 * time.Hour, wantLabels: []string{"stale"}, wantState: "open"},
+		{name: "recently rotten stays open", labels: []string{"rotten"}, updated: 24 * time.Hour, wantLabels: []string{"rotten"}, wantState: "open"},
+		{name: "rotten gets closed", labels: []string{"rotten"}, updated: 100 * 24 * time.Hour, wantLabels: []string{"rotten"}, wantState: "closed", wantComments: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.New(fake.Fixture{Repos: map[string]fake.RepoFixture{
+				"org/repo": {
+					Labels: []fake.LabelFixture{{Name: "stale"}, {Name: "rotten"}},
+					Issues: []fake.IssueFixture{{
+						Number:    1,
+						Title:     "issue",
+						State:     "open",
+						Labels:    tt.labels,
+						CreatedAt: now.Add(-tt.updated),
+						UpdatedAt: now.Add(-tt.updated),
+					}},
+				},
+			}})
+			opts := Opts{
+				StaleDuration:  30 * 24 * time.Hour,
+				StaleLabel:     "stale",
+				RottenDuration: 60 * 24 * time.Hour,
+				RottenLabel:    "rotten",
+				IssueSelector:  issues.Selector{Repo: "org/repo", State: "open"},
+			}
+			if err := Run(context.Background(), client, opts, now); err != nil {
+				t.Fatal(err)
+			}
+
+			issue, err := client.GetIssue(context.Background(), "org/repo", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *issue.State != tt.wantState {
+				t.Errorf("state is %s, want %s", *issue.State, tt.wantState)
+			}
+			var labels []string
+			for _, l := range issue.Labels {
+				labels = append(labels, l.GetName())
+			}
+			if !slices.Equal(labels, tt.wantLabels) {
+				t.Errorf("labels are %v, want %v", labels, tt.wantLabels)
+			}
+			comments, err := client.Comments("org/repo", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(comments) != tt.wantComments {
+				t.Errorf("got %d comments, want %d", len(comments), tt.wantComments)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package metasync
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+// noAccess revokes in a layer the access granted by the layers below it.
+const noAccess = "none"
+
+// AccessConf maps team slugs and collaborator logins, organization members included, to their permission: read,
+// triage, write, maintain or admin. Once Teams or Collaborators is set in a layer, the teams or collaborators missing
+// from it lose the access granted to them on the repository, an unset one isn't managed.
+type AccessConf struct {
+	Teams         map[string]string `yaml:"teams,omitempty"`
+	Collaborators map[string]string `yaml:"collaborators,omitempty"`
+
+	pos position
+}
+
+func (a *AccessConf) UnmarshalYAML(value *yaml.Node) error {
+	type plain AccessConf
+	if err := value.Decode((*plain)(a)); err != nil {
+		return err
+	}
+	a.pos = positionOf(value)
+	return nil
+}
+
+// merge returns a with the grants of over replacing its own.
+func (a *AccessConf) merge(over *AccessConf) *AccessConf {
+	if over == nil {
+		return a
+	}
+	if a == nil {
+		return over
+	}
+	return &AccessConf{Teams: mergeGrants(a.Teams, over.Teams), Collaborators: mergeGrants(a.Collaborators, over.Collaborators), pos: over.pos}
+}
+
+// mergeGrants keeps grants nil when no layer sets them, so they stay unmanaged.
+func mergeGrants(base map[string]string, over map[string]string) map[string]string {
+	if over == nil {
+		return base
+	}
+	res := maps.Clone(base)
+	if res == nil {
+		res = map[string]string{}
+	}
+	maps.Copy(res, over)
+	return res
+}
+
+func syncAccess(ctx context.Context, client api.Client, repo string, conf Conf) error {
+	if conf.Access == nil {
+		return nil
+	}
+	logger := slog.With(slog.String("repo", repo))
+	logger.LogAttrs(ctx, slog.LevelInfo, "sync access")
+
+	if conf.Access.Teams != nil {
+		teams, err := client.ListTeamAccess(ctx, repo)
+		if err != nil {
+			return err
+		}
+		if err := reconcileAccess(ctx, logger.With(slog.String("kind", "team")), teams, conf.Access.Teams,
+			func(name string, permission string) error { return client.SetTeamAccess(ctx, repo, name, permission) },
+			func(name string) error { return client.RemoveTeamAccess(ctx, repo, name) },
+		); err != nil {
+			return err
+		}
+	}
+
+	if conf.Access.Collaborators == nil {
+		return nil
+	}
+	collaborators, err := client.ListCollaboratorAccess(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return reconcileAccess(ctx, logger.With(slog.String("kind", "collaborator")), collaborators, conf.Access.Collaborators,
+		func(name string, permission string) error {
+			return client.SetCollaboratorAccess(ctx, repo, name, permission)
+		},
+		func(name string) error { return client.RemoveCollaboratorAccess(ctx, repo, name) },
+	)
+}
+
+// reconcileAccess grants, changes and revokes access so the current grants match the declared ones.
+func reconcileAccess(
+	ctx context.Context,
+	logger *slog.Logger,
+	current []*api.Access,
+	declared map[string]string,
+	set func(name string, permission string) error,
+	remove func(name string) error,
+) error {
+	// Team slugs and logins are case-insensitive.
+	want := map[string]string{}
+	names := map[string]string{}
+	for name, permission := range declared {
+		want[strings.ToLower(name)] = permission
+		names[strings.ToLower(name)] = name
+	}
+	have := map[string]bool{}
+	for _, a := range current {
+		key := strings.ToLower(a.Name)
+		have[key] = true
+		permission := want[key]
+		switch {
+		case revokes(a, want):
+			logger.LogAttrs(ctx, slog.LevelInfo, "revoking access", slog.String("name", a.Name),
+				slog.String("permission", a.Permission), slog.Bool("pending", a.Pending))
+			if err := remove(a.Name); err != nil {
+				return err
+			}
+		case permission != a.Permission:
+			logger.LogAttrs(ctx, slog.LevelInfo, "changing access", slog.String("name", a.Name),
+				slog.String("from", a.Permission), slog.String("to", permission), slog.Bool("pending", a.Pending))
+			if err := set(a.Name, permission); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(want)) {
+		if have[key] || want[key] == noAccess {
+			continue
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "granting access", slog.String("name", names[key]), slog.String("permission", want[key]))
+		if err := set(names[key], want[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokes tells whether a is revoked by want, the declared grants keyed by lowercase name.
+func revokes(a *api.Access, want map[string]string) bool {
+	permission, ok := want[strings.ToLower(a.Name)]
+	return !ok || permission == noAccess
+}
+
+// accessRevocations counts the grants syncing access would revoke.
+func accessRevocations(ctx context.Context, client api.Client, repo string, access *AccessConf) (int, error) {
+	if access == nil {
+		return 0, nil
+	}
+	count := func(list func(context.Context, string) ([]*api.Access, error), declared map[string]string) (int, error) {
+		if declared == nil {
+			return 0, nil
+		}
+		current, err := list(ctx, repo)
+		if err != nil {
+			return 0, err
+		}
+		want := map[string]string{}
+		for name, permission := range declared {
+			want[strings.ToLower(name)] = permission
+		}
+		n := 0
+		for _, a := range current {
+			if revokes(a, want) {
+				n += 1
+			}
+		}
+		return n, nil
+	}
+	teams, err := count(client.ListTeamAccess, access.Teams)
+	if err != nil {
+		return 0, err
+	}
+	collaborators, err := count(client.ListCollaboratorAccess, access.Collaborators)
+	return teams + collaborators, err
+}
+
+func (v *validator) access(a *AccessConf) {
+	if a == nil {
+		return
+	}
+	check := func(kind string, grants map[string]string) {
+		for _, name := range slices.Sorted(maps.Keys(grants)) {
+			permission := grants[name]
+			if permission != noAccess && !slices.Contains(api.Permissions, permission) {
+				v.report(a.pos, "%s %q has an invalid permission %q, expected one of %s or %s",
+					kind, name, permission, strings.Join(api.Permissions, ", "), noAccess)
+			}
+		}
+	}
+	check("team", a.Teams)
+	check("collaborator", a.Collaborators)
+}
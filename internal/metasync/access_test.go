@@ -0,0 +1,135 @@
+package metasync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+const accessFixture = `
+repos:
+  acme/widgets:
+    teams: {platform: write, docs: read}
+    collaborators: {alice: read}
+    invitations: {bob: read}
+`
+
+// access lists the grants of repo as `name permission`, invitations as `name permission pending`.
+func access(t *testing.T, list func(context.Context, string) ([]*api.Access, error)) []string {
+	t.Helper()
+	grants, err := list(context.Background(), "acme/widgets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, a := range grants {
+		s := a.Name + " " + a.Permission
+		if a.Pending {
+			s += " pending"
+		}
+		res = append(res, s)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func TestSyncAccess(t *testing.T) {
+	tests := []struct {
+		name              string
+		conf              string
+		wantErr           bool
+		wantTeams         []string
+		wantCollaborators []string
+		wantInvites       int
+	}{
+		{
+			name: "teams only",
+			conf: `
+repos: [acme/widgets]
+config:
+  access: {teams: {platform: admin}}
+`,
+			wantTeams:         []string{"platform admin"},
+			wantCollaborators: []string{"alice read", "bob read pending"},
+		},
+		{
+			name: "collaborators only",
+			conf: `
+repos: [acme/widgets]
+config:
+  access: {collaborators: {alice: write}}
+`,
+			wantTeams:         []string{"docs read", "platform write"},
+			wantCollaborators: []string{"alice write"},
+		},
+		{
+			name: "layers managing one each",
+			conf: `
+repos: [acme/widgets]
+config:
+  access: {teams: {platform: write}}
+orgs:
+  acme:
+    access: {collaborators: {alice: read, bob: read}}
+`,
+			wantTeams:         []string{"platform write"},
+			wantCollaborators: []string{"alice read", "bob read pending"},
+		},
+		{
+			name: "empty teams revoke all",
+			conf: `
+repos: [acme/widgets]
+config:
+  access: {teams: {}}
+`,
+			wantTeams:         nil,
+			wantCollaborators: []string{"alice read", "bob read pending"},
+		},
+		{
+			name: "invitations updated rather than sent again",
+			conf: `
+repos: [acme/widgets]
+config:
+  access: {collaborators: {alice: read, bob: write, carol: triage}}
+`,
+			wantTeams:         []string{"docs read", "platform write"},
+			wantCollaborators: []string{"alice read", "bob write pending", "carol triage pending"},
+			wantInvites:       1,
+		},
+		{
+			name: "revocations count as deletions",
+			conf: `
+repos: [acme/widgets]
+prune: {maxDeletions: 2}
+config:
+  access: {teams: {}, collaborators: {}}
+`,
+			wantErr:           true,
+			wantTeams:         []string{"docs read", "platform write"},
+			wantCollaborators: []string{"alice read", "bob read pending"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFake(t, accessFixture)
+			// The second run must find nothing left to do.
+			for range 2 {
+				err := run(t, client, tt.conf, Opts{})
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("got error %v, want one: %v", err, tt.wantErr)
+				}
+			}
+			if got := access(t, client.ListTeamAccess); !slices.Equal(got, tt.wantTeams) {
+				t.Errorf("teams are %q, want %q", got, tt.wantTeams)
+			}
+			if got := access(t, client.ListCollaboratorAccess); !slices.Equal(got, tt.wantCollaborators) {
+				t.Errorf("collaborators are %q, want %q", got, tt.wantCollaborators)
+			}
+			if got := client.Invites("acme/widgets"); got != tt.wantInvites {
+				t.Errorf("sent %d invitations, want %d", got, tt.wantInvites)
+			}
+		})
+	}
+}
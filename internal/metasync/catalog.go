@@ -0,0 +1,180 @@
+package metasync
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	CatalogMarkdown = "markdown"
+	CatalogHTML     = "html"
+)
+
+// otherLabels is the group of the labels without a prefix.
+const otherLabels = "other"
+
+//go:embed catalog.html.tmpl
+var catalogHTML string
+
+var catalogTemplate = template.Must(template.New("catalog").Parse(catalogHTML))
+
+type CatalogOpts struct {
+	// Format is markdown or html.
+	Format string
+}
+
+func (o CatalogOpts) Validate() error {
+	if o.Format != CatalogMarkdown && o.Format != CatalogHTML {
+		return fmt.Errorf("invalid format %q, expected %s or %s", o.Format, CatalogMarkdown, CatalogHTML)
+	}
+	return nil
+}
+
+// catalogGroup are the labels sharing a prefix such as `kind/`.
+type catalogGroup struct {
+	Prefix string
+	Labels []catalogLabel
+}
+
+// catalogLabel is a label as some repositories get it, a label with another color or description in other
+// repositories has another catalogLabel.
+type catalogLabel struct {
+	Name        string
+	Color       string
+	Description string
+	Previously  []string
+	// Repos are the repositories or targets getting the label, empty when all of them do.
+	Repos []string
+}
+
+// TextColor is black or white, whichever is readable on the color of the label.
+func (l catalogLabel) TextColor() string {
+	rgb, err := strconv.ParseUint(l.Color, 16, 32)
+	if err != nil {
+		return "#000000"
+	}
+	r, g, b := float64(rgb>>16&0xff), float64(rgb>>8&0xff), float64(rgb&0xff)
+	if 0.299*r+0.587*g+0.114*b > 150 {
+		return "#000000"
+	}
+	return "#ffffff"
+}
+
+func labelPrefix(name string) string {
+	if i := strings.IndexAny(name, "/:"); i > 0 {
+		return name[:i+1]
+	}
+	return otherLabels
+}
+
+// catalog lists the labels each repository gets, without resolving targets: a pattern or a topic is listed as is.
+func (c ConfRoot) catalog() []catalogGroup {
+	repos := c.Repos
+	if len(repos) == 0 {
+		repos = []RepoDef{{}}
+	}
+	type key struct{ name, color, description string }
+	labels := map[key]*catalogLabel{}
+	var keys []key
+	for _, repo := range repos {
+		for _, def := range c.Resolve(repo).Labels {
+			if def.Delete {
+				continue
+			}
+			k := key{strings.ToLower(def.Name), strings.ToLower(def.Color), def.Description}
+			l, ok := labels[k]
+			if !ok {
+				l = &catalogLabel{Name: def.Name, Color: strings.ToLower(def.Color), Description: def.Description, Previously: def.Previously}
+				labels[k] = l
+				keys = append(keys, k)
+			}
+			if repo.Name != "" {
+				l.Repos = append(l.Repos, repo.Name)
+			}
+		}
+	}
+
+	byPrefix := map[string]*catalogGroup{}
+	for _, k := range keys {
+		l := labels[k]
+		if len(l.Repos) == len(c.Repos) {
+			l.Repos = nil
+		}
+		prefix := labelPrefix(l.Name)
+		if byPrefix[prefix] == nil {
+			byPrefix[prefix] = &catalogGroup{Prefix: prefix}
+		}
+		byPrefix[prefix].Labels = append(byPrefix[prefix].Labels, *l)
+	}
+	var res []catalogGroup
+	for _, prefix := range sortedKeys(byPrefix) {
+		g := byPrefix[prefix]
+		sort.SliceStable(g.Labels, func(i, j int) bool {
+			return strings.ToLower(g.Labels[i].Name) < strings.ToLower(g.Labels[j].Name)
+		})
+		res = append(res, *g)
+	}
+	// Labels without a prefix come last.
+	slices.SortStableFunc(res, func(a, b catalogGroup) int {
+		switch {
+		case a.Prefix == otherLabels && b.Prefix != otherLabels:
+			return 1
+		case a.Prefix != otherLabels && b.Prefix == otherLabels:
+			return -1
+		}
+		return 0
+	})
+	return res
+}
+
+// WriteCatalog writes the labels of the configuration at path grouped by prefix, with their description and the
+// repositories getting them, without calling GitHub.
+func WriteCatalog(w io.Writer, path string, opts CatalogOpts) error {
+	conf, err := parseConf(path)
+	if err != nil {
+		return err
+	}
+	if diagnostics := conf.validate(); len(diagnostics) > 0 {
+		return invalidConfError(diagnostics)
+	}
+	groups := conf.catalog()
+	if opts.Format == CatalogHTML {
+		return catalogTemplate.Execute(w, groups)
+	}
+	return writeCatalogMarkdown(w, groups)
+}
+
+// markdownCell escapes what would break a table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func writeCatalogMarkdown(w io.Writer, groups []catalogGroup) error {
+	var b strings.Builder
+	b.WriteString("# Labels\n\n")
+	b.WriteString("<!-- Generated by meta-sync from its configuration, edit the configuration instead. -->\n")
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n## %s\n\n", markdownCell(g.Prefix))
+		b.WriteString("| Label | Color | Description | Repositories |\n")
+		b.WriteString("|---|---|---|---|\n")
+		for _, l := range g.Labels {
+			description := markdownCell(l.Description)
+			if len(l.Previously) > 0 {
+				description = strings.TrimSpace(description + " (previously `" + strings.Join(l.Previously, "`, `") + "`)")
+			}
+			repos := "all"
+			if len(l.Repos) > 0 {
+				repos = markdownCell(strings.Join(l.Repos, ", "))
+			}
+			fmt.Fprintf(&b, "| `%s` | `#%s` | %s | %s |\n", markdownCell(l.Name), l.Color, description, repos)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,330 @@
+package metasync
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+type ConfRoot struct {
+	// Include lists files, URLs or directories of YAML files merged into this configuration. Relative paths are
+	// relative to the including file.
+	Include []string `yaml:"include,omitempty"`
+	// Repos are `org/repo` names, `org/<pattern>` (e.g. `org/service-*`) or `topic:<org>/<topic>` targets
+	// resolved by listing the repositories of their organization at run time.
+	Repos []RepoDef `yaml:"repos,omitempty"`
+	// Targets tunes how the patterns and topics of Repos are resolved.
+	Targets TargetsConf `yaml:"targets,omitempty"`
+	// Config applies to every repository.
+	Config Conf `yaml:"config,omitempty"`
+	// Orgs apply on top of Config to the repositories of each organization.
+	Orgs map[string]Layer `yaml:"orgs,omitempty"`
+	// Groups apply on top of Orgs to the repositories they match, in the order of their names.
+	Groups map[string]Group `yaml:"groups,omitempty"`
+	Prune  PruneConf        `yaml:"prune,omitempty"`
+}
+
+type Conf struct {
+	Labels []LabelDef `yaml:"labels,omitempty"`
+	// LabelGroups are expanded into labels, after Labels.
+	LabelGroups []LabelGroupDef `yaml:"labelGroups,omitempty"`
+	Milestones  []MilestoneDef  `yaml:"milestones,omitempty"`
+	// MilestoneSchedules are expanded into milestones when syncing.
+	MilestoneSchedules []MilestoneScheduleDef `yaml:"milestoneSchedules,omitempty"`
+	Settings           *RepoSettings          `yaml:"settings,omitempty"`
+	// BranchProtection is synced as rulesets, one per pattern.
+	BranchProtection []BranchProtectionDef `yaml:"branchProtection,omitempty"`
+	Access           *AccessConf           `yaml:"access,omitempty"`
+	// Files are proposed with a pull request to the repositories where they differ.
+	Files []FileDef `yaml:"files,omitempty"`
+	// IssueForms are generated from the labels and synced like Files.
+	IssueForms []IssueFormDef `yaml:"issueForms,omitempty"`
+}
+
+// Layer adds or overrides labels and milestones of the layers below it, by name, and can exclude some of them.
+type Layer struct {
+	Conf    `yaml:",inline"`
+	Exclude Exclude `yaml:"exclude,omitempty"`
+}
+
+type Exclude struct {
+	Labels     []string `yaml:"labels,omitempty"`
+	Milestones []string `yaml:"milestones,omitempty"`
+	// BranchProtection are the patterns of the branch protections to exclude.
+	BranchProtection []string `yaml:"branchProtection,omitempty"`
+	// Files are the paths of the files to exclude.
+	Files []string `yaml:"files,omitempty"`
+	// IssueForms are the file names of the issue forms to exclude.
+	IssueForms []string `yaml:"issueForms,omitempty"`
+}
+
+type Group struct {
+	Layer `yaml:",inline"`
+	// Repos are patterns (e.g. `org/frontend-*`) of the repositories in the group.
+	Repos []Target `yaml:"repos"`
+}
+
+// setFile records source as the file defining the group.
+func (g *Group) setFile(source string) {
+	g.Layer.setFile(source)
+	for i := range g.Repos {
+		g.Repos[i].pos.file = source
+	}
+}
+
+// RepoDef is a repository to sync, either just its `org/repo` name or a mapping with per repository settings.
+type RepoDef struct {
+	Name string `yaml:"name"`
+	// Prune overrides prune.enabled for this repository.
+	Prune *bool `yaml:"prune,omitempty"`
+	// Layer is applied last, on top of all the other layers.
+	Layer `yaml:",inline"`
+
+	pos position
+}
+
+func (r *RepoDef) UnmarshalYAML(value *yaml.Node) error {
+	r.pos = positionOf(value)
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&r.Name)
+	}
+	type plain RepoDef
+	pos := r.pos
+	if err := value.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	r.pos = pos
+	return nil
+}
+
+func (r RepoDef) MarshalYAML() (any, error) {
+	if r.Prune == nil && r.Layer.isZero() {
+		return r.Name, nil
+	}
+	type plain RepoDef
+	return plain(r), nil
+}
+
+// Resolve merges the layers which apply to repo: config, its organization, its groups and itself.
+func (c ConfRoot) Resolve(repo RepoDef) Conf {
+	res := Conf{
+		Labels:             c.Config.labels(),
+		Milestones:         append([]MilestoneDef{}, c.Config.Milestones...),
+		MilestoneSchedules: append([]MilestoneScheduleDef{}, c.Config.MilestoneSchedules...),
+		Settings:           c.Config.Settings,
+
+		BranchProtection: append([]BranchProtectionDef{}, c.Config.BranchProtection...),
+		Access:           c.Config.Access,
+		Files:            append([]FileDef{}, c.Config.Files...),
+		IssueForms:       append([]IssueFormDef{}, c.Config.IssueForms...),
+	}
+	org, _, _ := utils.OrgRepo(repo.Name)
+	if layer, ok := c.org(org); ok {
+		res = layer.apply(res)
+	}
+	groups := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		if c.Groups[name].matches(repo.Name) {
+			res = c.Groups[name].apply(res)
+		}
+	}
+	return repo.apply(res)
+}
+
+// org returns the layer of org, organization names are case-insensitive on GitHub.
+func (c ConfRoot) org(org string) (Layer, bool) {
+	if layer, ok := c.Orgs[org]; ok {
+		return layer, true
+	}
+	for _, name := range sortedKeys(c.Orgs) {
+		if strings.EqualFold(name, org) {
+			return c.Orgs[name], true
+		}
+	}
+	return Layer{}, false
+}
+
+// matches ignores case like GitHub does for repository names.
+func (g Group) matches(repo string) bool {
+	for _, pattern := range g.Repos {
+		if ok, _ := path.Match(strings.ToLower(pattern.Name), strings.ToLower(repo)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Layer) isZero() bool {
+	return len(l.Labels) == 0 && len(l.LabelGroups) == 0 && len(l.Milestones) == 0 && len(l.MilestoneSchedules) == 0 && l.Settings == nil && len(l.BranchProtection) == 0 && l.Access == nil &&
+		len(l.Files) == 0 && len(l.IssueForms) == 0 && len(l.Exclude.Labels) == 0 && len(l.Exclude.Milestones) == 0 &&
+		len(l.Exclude.BranchProtection) == 0 && len(l.Exclude.Files) == 0 && len(l.Exclude.IssueForms) == 0
+}
+
+// apply keeps the position of overridden labels and milestones so the result doesn't depend on map ordering.
+func (l Layer) apply(conf Conf) Conf {
+	excludedLabels := make([]string, 0, len(l.Exclude.Labels))
+	for _, name := range l.Exclude.Labels {
+		excludedLabels = append(excludedLabels, strings.ToLower(name))
+	}
+	return Conf{
+		Settings: conf.Settings.merge(l.Settings),
+		Access:   conf.Access.merge(l.Access),
+		// Label names are case-insensitive on GitHub.
+		Labels:     overlay(conf.Labels, l.labels(), func(def LabelDef) string { return strings.ToLower(def.Name) }, excludedLabels),
+		Milestones: overlay(conf.Milestones, l.Milestones, func(def MilestoneDef) string { return def.Title }, l.Exclude.Milestones),
+		// Schedules are kept to close their past milestones, see Conf.pastMilestone.
+		MilestoneSchedules: overlay(conf.MilestoneSchedules, l.MilestoneSchedules, func(def MilestoneScheduleDef) string { return def.Title }, nil),
+		BranchProtection:   overlay(conf.BranchProtection, l.BranchProtection, func(def BranchProtectionDef) string { return def.Pattern }, l.Exclude.BranchProtection),
+		Files:              overlay(conf.Files, l.Files, func(def FileDef) string { return def.Path }, l.Exclude.Files),
+		IssueForms:         overlay(conf.IssueForms, l.IssueForms, func(def IssueFormDef) string { return def.File }, l.Exclude.IssueForms),
+	}
+}
+
+// overlay replaces the definitions of base by those of over with the same key, in place, drops the excluded keys
+// and appends the new definitions of over.
+func overlay[T any](base []T, over []T, key func(T) string, exclude []string) []T {
+	excluded := map[string]bool{}
+	for _, k := range exclude {
+		excluded[k] = true
+	}
+	overrides := map[string]T{}
+	for _, def := range over {
+		overrides[key(def)] = def
+	}
+	var res []T
+	for _, def := range base {
+		k := key(def)
+		if override, ok := overrides[k]; ok {
+			res = append(res, override)
+			delete(overrides, k)
+		} else if !excluded[k] {
+			res = append(res, def)
+		}
+	}
+	for _, def := range over {
+		if _, ok := overrides[key(def)]; ok {
+			res = append(res, def)
+		}
+	}
+	return res
+}
+
+// position is where something is defined in the configuration, for diagnostics.
+type position struct {
+	file   string
+	line   int
+	column int
+}
+
+func positionOf(value *yaml.Node) position {
+	return position{line: value.Line, column: value.Column}
+}
+
+type Time struct {
+	time.Time
+	// invalid holds a value which isn't a date, reported when validating the configuration.
+	invalid string
+}
+
+func (t *Time) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		*t = Time{invalid: s}
+		return nil
+	}
+	*t = Time{
+		Time: parsed.Add(time.Hour * 24),
+	}
+	return nil
+}
+
+// MarshalYAML is the inverse of UnmarshalYAML.
+func (t Time) MarshalYAML() (any, error) {
+	if t.invalid != "" {
+		return t.invalid, nil
+	}
+	return t.Add(-time.Hour * 24).Format(time.DateOnly), nil
+}
+
+type MilestoneDef struct {
+	Title       string `yaml:"title"`
+	Closed      bool   `yaml:"closed,omitempty"`
+	DueDate     Time   `yaml:"dueDate,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Delete      bool   `yaml:"delete,omitempty"`
+
+	pos position
+}
+
+func (m *MilestoneDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain MilestoneDef
+	if err := value.Decode((*plain)(m)); err != nil {
+		return err
+	}
+	m.pos = positionOf(value)
+	return nil
+}
+
+type LabelDef struct {
+	Name        string `yaml:"name"`
+	Color       string `yaml:"color"`
+	Description string `yaml:"description,omitempty"`
+	Delete      bool   `yaml:"delete,omitempty"`
+	// Previously lists the former names of the label, existing labels with these names get renamed or merged.
+	Previously []string `yaml:"previously,omitempty"`
+
+	pos position
+}
+
+func (l *LabelDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain LabelDef
+	if err := value.Decode((*plain)(l)); err != nil {
+		return err
+	}
+	l.pos = positionOf(value)
+	return nil
+}
+
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+func readSource(path string) ([]byte, error) {
+	if !isURL(path) {
+		return os.ReadFile(path)
+	}
+	r, err := http.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 {
+		return nil, fmt.Errorf("invalid status code: %d", r.StatusCode)
+	}
+	return io.ReadAll(r.Body)
+}
+
+func parseConf(path string) (ConfRoot, error) {
+	l := &confLoader{visiting: map[string]bool{}, loaded: map[string]bool{}, origins: map[string]string{}}
+	out := ConfRoot{}
+	if err := l.load(&out, path); err != nil {
+		return out, err
+	}
+	return out, nil
+}
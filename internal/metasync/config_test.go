@@ -0,0 +1,133 @@
+package metasync
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	conf := `
+config:
+  labels:
+    - {name: kind/bug, color: d73a4a}
+    - {name: kind/feature, color: a2eeef}
+    - {name: triage, color: ededed}
+  milestones:
+    - {title: v1.0}
+  files:
+    - {path: CODEOWNERS, content: "* @acme/all"}
+  branchProtection:
+    - {pattern: main, requiredReviews: 1}
+orgs:
+  Acme:
+    labels:
+      - {name: KIND/BUG, color: ff0000}
+      - {name: org-only, color: 000000}
+    exclude:
+      labels: [Triage]
+groups:
+  b-frontend:
+    repos: [acme/Web-*]
+    labels:
+      - {name: area/ui, color: 0000ff}
+    files:
+      - {path: CODEOWNERS, content: "* @acme/frontend"}
+  a-all:
+    repos: ["*/*"]
+    milestones:
+      - {title: v2.0}
+    exclude:
+      branchProtection: [main]
+repos:
+  - acme/web-app
+  - name: other/lib
+    labels:
+      - {name: kind/feature, color: 00ff00}
+    exclude:
+      milestones: [v1.0]
+      files: [CODEOWNERS]
+`
+	root, err := parseConf(writeConf(t, conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		repo           RepoDef
+		wantLabels     []string
+		wantMilestones []string
+		wantFiles      []string
+		wantProtection []string
+	}{
+		{
+			repo:           root.Repos[0],
+			wantLabels:     []string{"KIND/BUG ff0000", "kind/feature a2eeef", "org-only 000000", "area/ui 0000ff"},
+			wantMilestones: []string{"v1.0", "v2.0"},
+			wantFiles:      []string{"CODEOWNERS * @acme/frontend"},
+		},
+		{
+			repo:           root.Repos[1],
+			wantLabels:     []string{"kind/bug d73a4a", "kind/feature 00ff00", "triage ededed"},
+			wantMilestones: []string{"v2.0"},
+		},
+		{
+			repo:           RepoDef{Name: "acme/api"},
+			wantLabels:     []string{"KIND/BUG ff0000", "kind/feature a2eeef", "org-only 000000"},
+			wantMilestones: []string{"v1.0", "v2.0"},
+			wantFiles:      []string{"CODEOWNERS * @acme/all"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repo.Name, func(t *testing.T) {
+			res := root.Resolve(tt.repo)
+			var labels, milestones, files, protection []string
+			for _, def := range res.Labels {
+				labels = append(labels, def.Name+" "+def.Color)
+			}
+			for _, def := range res.Milestones {
+				milestones = append(milestones, def.Title)
+			}
+			for _, def := range res.Files {
+				files = append(files, def.Path+" "+def.Content)
+			}
+			for _, def := range res.BranchProtection {
+				protection = append(protection, def.Pattern)
+			}
+			if !slices.Equal(labels, tt.wantLabels) {
+				t.Errorf("labels are %q, want %q", labels, tt.wantLabels)
+			}
+			if !slices.Equal(milestones, tt.wantMilestones) {
+				t.Errorf("milestones are %q, want %q", milestones, tt.wantMilestones)
+			}
+			if !slices.Equal(files, tt.wantFiles) {
+				t.Errorf("files are %q, want %q", files, tt.wantFiles)
+			}
+			if !slices.Equal(protection, tt.wantProtection) {
+				t.Errorf("branch protections are %q, want %q", protection, tt.wantProtection)
+			}
+		})
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	key := func(s string) string { return s[:1] }
+	tests := []struct {
+		name    string
+		base    []string
+		over    []string
+		exclude []string
+		want    []string
+	}{
+		{name: "empty", want: nil},
+		{name: "added after the base", base: []string{"a1", "b1"}, over: []string{"c2"}, want: []string{"a1", "b1", "c2"}},
+		{name: "overridden in place", base: []string{"a1", "b1", "c1"}, over: []string{"d2", "b2"}, want: []string{"a1", "b2", "c1", "d2"}},
+		{name: "excluded", base: []string{"a1", "b1"}, exclude: []string{"a", "z"}, want: []string{"b1"}},
+		{name: "overrides win over exclusions", base: []string{"a1", "b1"}, over: []string{"a2"}, exclude: []string{"a"}, want: []string{"a2", "b1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlay(tt.base, tt.over, key, tt.exclude); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
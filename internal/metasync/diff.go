@@ -0,0 +1,305 @@
+package metasync
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/mutation"
+)
+
+// ErrDrift is returned when repositories don't match the configuration.
+var ErrDrift = errors.New("repositories drifted from the configuration")
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+type diffPrinter struct {
+	w     io.Writer
+	color bool
+}
+
+func (p diffPrinter) colored(color string, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (p diffPrinter) header(symbol string, color string, kind string, name string) {
+	fmt.Fprintf(p.w, "  %s %s %q\n", p.colored(color, symbol), kind, name)
+}
+
+func (p diffPrinter) value(field string, value string) {
+	fmt.Fprintf(p.w, "      %-15s %s\n", field+":", value)
+}
+
+func (p diffPrinter) change(field string, before string, after string) {
+	if before == after {
+		return
+	}
+	fmt.Fprintf(p.w, "      %-15s %s → %s\n", field+":", p.colored(colorRed, before), p.colored(colorGreen, after))
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+func formatDue(t *time.Time) string {
+	if t == nil {
+		return "none"
+	}
+	return t.UTC().Format(time.DateOnly)
+}
+
+type diffCount struct {
+	create, update, delete int
+}
+
+// Diff prints the changes of ops per repo and field followed by a summary table. It returns whether there was any
+// change at all, meaning the repositories drifted from the configuration.
+func Diff(w io.Writer, ops []mutation.Op, color bool) bool {
+	p := diffPrinter{w: w, color: color}
+	byRepo := map[string][]mutation.Op{}
+	var repos []string
+	for _, op := range ops {
+		if _, ok := byRepo[op.Repo]; !ok {
+			repos = append(repos, op.Repo)
+		}
+		byRepo[op.Repo] = append(byRepo[op.Repo], op)
+	}
+	sort.Strings(repos)
+
+	counts := map[string]*diffCount{}
+	for _, repo := range repos {
+		repoOps := byRepo[repo]
+		// Syncing is concurrent so the order of the operations isn't stable.
+		sort.SliceStable(repoOps, func(i, j int) bool {
+			return diffSortKey(repoOps[i]) < diffSortKey(repoOps[j])
+		})
+		fmt.Fprintln(w, repo)
+		c := &diffCount{}
+		counts[repo] = c
+		for _, op := range repoOps {
+			switch {
+			case strings.HasPrefix(string(op.Kind), "create-"):
+				c.create += 1
+			case strings.HasPrefix(string(op.Kind), "delete-"):
+				c.delete += 1
+			default:
+				c.update += 1
+			}
+			p.op(op)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(repos) == 0 {
+		fmt.Fprintln(w, "No changes, repositories match the configuration.")
+		return false
+	}
+	fmt.Fprintln(w, "Summary")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPO\tCREATE\tUPDATE\tDELETE")
+	for _, repo := range repos {
+		c := counts[repo]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", repo, c.create, c.update, c.delete)
+	}
+	_ = tw.Flush()
+	return true
+}
+
+func diffSortKey(op mutation.Op) string {
+	name := op.Name
+	switch {
+	case op.After != nil && op.After.Label != nil:
+		name = op.After.Label.Name
+	case op.After != nil && op.After.Milestone != nil:
+		name = op.After.Milestone.Title
+	case op.Before != nil && op.Before.Milestone != nil:
+		name = op.Before.Milestone.Title
+	case op.After != nil && op.After.Ruleset != nil:
+		name = op.After.Ruleset.Name
+	case op.Issue != 0:
+		name = fmt.Sprintf("%010d", op.Issue)
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(string(op.Kind), "create-"), "update-"), "delete-") + "/" + name
+}
+
+func accessKind(kind mutation.Kind) string {
+	if strings.HasSuffix(string(kind), "-team-access") {
+		return "team"
+	}
+	return "collaborator"
+}
+
+func rulesetFields(r *mutation.Ruleset) [][2]string {
+	var bypass []string
+	for _, actor := range r.BypassActors {
+		bypass = append(bypass, fmt.Sprintf("%s:%d", actor.Type, actor.ID))
+	}
+	return [][2]string{
+		{"enforcement", r.Enforcement},
+		{"branches", strings.Join(r.Include, ",")},
+		{"excluded", strings.Join(r.Exclude, ",")},
+		{"pull request", fmt.Sprint(r.RequirePullRequest)},
+		{"reviews", fmt.Sprint(r.RequiredReviews)},
+		{"dismiss stale", fmt.Sprint(r.DismissStaleReviews)},
+		{"code owners", fmt.Sprint(r.RequireCodeOwnerReviews)},
+		{"checks", strings.Join(r.RequiredStatusChecks, ",")},
+		{"strict checks", fmt.Sprint(r.StrictStatusChecks)},
+		{"linear", fmt.Sprint(r.LinearHistory)},
+		{"force pushes", fmt.Sprint(!r.BlockForcePushes)},
+		{"deletions", fmt.Sprint(!r.BlockDeletions)},
+		{"restricted", fmt.Sprint(r.RestrictUpdates)},
+		{"bypass", strings.Join(bypass, ",")},
+	}
+}
+
+// lineChanges counts the lines only after has and only before has.
+func lineChanges(before string, after string) (int, int) {
+	counts := map[string]int{}
+	for _, line := range strings.Split(before, "\n") {
+		counts[line] += 1
+	}
+	added := 0
+	for _, line := range strings.Split(after, "\n") {
+		if counts[line] > 0 {
+			counts[line] -= 1
+		} else {
+			added += 1
+		}
+	}
+	removed := 0
+	for _, n := range counts {
+		removed += n
+	}
+	return added, removed
+}
+
+func (p diffPrinter) op(op mutation.Op) {
+	switch op.Kind {
+	case mutation.CreateLabel:
+		l := op.After.Label
+		p.header("+", colorGreen, "label", l.Name)
+		p.value("color", l.Color)
+		p.value("description", quote(l.Description))
+	case mutation.UpdateLabel:
+		p.header("~", colorYellow, "label", op.Name)
+		before, after := &mutation.Label{}, op.After.Label
+		if op.Before != nil {
+			before = op.Before.Label
+		}
+		p.change("name", before.Name, after.Name)
+		p.change("color", strings.ToLower(before.Color), strings.ToLower(after.Color))
+		p.change("description", quote(before.Description), quote(after.Description))
+	case mutation.DeleteLabel:
+		p.header("-", colorRed, "label", op.Name)
+	case mutation.CreateMilestone:
+		m := op.After.Milestone
+		p.header("+", colorGreen, "milestone", m.Title)
+		p.value("state", m.State)
+		p.value("description", quote(m.Description))
+		p.value("due", formatDue(m.DueOn))
+	case mutation.UpdateMilestone:
+		before, after := &mutation.Milestone{}, op.After.Milestone
+		if op.Before != nil {
+			before = op.Before.Milestone
+		}
+		p.header("~", colorYellow, "milestone", before.Title)
+		p.change("title", before.Title, after.Title)
+		p.change("state", before.State, after.State)
+		p.change("description", quote(before.Description), quote(after.Description))
+		p.change("due", formatDue(before.DueOn), formatDue(after.DueOn))
+	case mutation.DeleteMilestone:
+		title := fmt.Sprintf("#%d", op.Number)
+		if op.Before != nil {
+			title = op.Before.Milestone.Title
+		}
+		p.header("-", colorRed, "milestone", title)
+	case mutation.UpdateIssueLabels:
+		p.header("~", colorYellow, "issue", fmt.Sprintf("#%d", op.Issue))
+		before := []string{}
+		if op.Before != nil {
+			before = op.Before.Labels
+		}
+		p.change("labels", strings.Join(before, ","), strings.Join(op.After.Labels, ","))
+	case mutation.UpdateRepository:
+		p.header("~", colorYellow, "settings", op.Repo)
+		before, after := &mutation.Repository{}, op.After.Repository
+		if op.Before != nil {
+			before = op.Before.Repository
+		}
+		p.change("description", quote(before.Description), quote(after.Description))
+		p.change("homepage", quote(before.Homepage), quote(after.Homepage))
+		p.change("topics", strings.Join(before.Topics, ","), strings.Join(after.Topics, ","))
+		p.change("issues", fmt.Sprint(before.HasIssues), fmt.Sprint(after.HasIssues))
+		p.change("wiki", fmt.Sprint(before.HasWiki), fmt.Sprint(after.HasWiki))
+		p.change("projects", fmt.Sprint(before.HasProjects), fmt.Sprint(after.HasProjects))
+		p.change("discussions", fmt.Sprint(before.HasDiscussions), fmt.Sprint(after.HasDiscussions))
+		p.change("merge commit", fmt.Sprint(before.AllowMergeCommit), fmt.Sprint(after.AllowMergeCommit))
+		p.change("squash merge", fmt.Sprint(before.AllowSquashMerge), fmt.Sprint(after.AllowSquashMerge))
+		p.change("rebase merge", fmt.Sprint(before.AllowRebaseMerge), fmt.Sprint(after.AllowRebaseMerge))
+		p.change("delete branch", fmt.Sprint(before.DeleteBranchOnMerge), fmt.Sprint(after.DeleteBranchOnMerge))
+		p.change("default branch", before.DefaultBranch, after.DefaultBranch)
+	case mutation.CreateRuleset:
+		r := op.After.Ruleset
+		p.header("+", colorGreen, "ruleset", r.Name)
+		for _, f := range rulesetFields(r) {
+			p.value(f[0], f[1])
+		}
+	case mutation.UpdateRuleset:
+		p.header("~", colorYellow, "ruleset", op.Name)
+		before := rulesetFields(&mutation.Ruleset{})
+		if op.Before != nil {
+			before = rulesetFields(op.Before.Ruleset)
+		}
+		for i, f := range rulesetFields(op.After.Ruleset) {
+			p.change(f[0], before[i][1], f[1])
+		}
+	case mutation.DeleteRuleset:
+		p.header("-", colorRed, "ruleset", op.Name)
+	case mutation.CreateTeamAccess, mutation.CreateCollaboratorAccess:
+		p.header("+", colorGreen, accessKind(op.Kind), op.Name)
+		p.value("permission", op.After.Permission)
+	case mutation.UpdateTeamAccess, mutation.UpdateCollaboratorAccess:
+		p.header("~", colorYellow, accessKind(op.Kind), op.Name)
+		before := ""
+		if op.Before != nil {
+			before = op.Before.Permission
+		}
+		p.change("permission", before, op.After.Permission)
+	case mutation.DeleteTeamAccess, mutation.DeleteCollaboratorAccess:
+		p.header("-", colorRed, accessKind(op.Kind), op.Name)
+		if op.Before != nil {
+			p.value("permission", op.Before.Permission)
+		}
+	case mutation.ProposeFiles:
+		p.header("~", colorYellow, "pull request", op.Name)
+		before := map[string]string{}
+		if op.Before != nil {
+			for _, f := range op.Before.Files {
+				before[f.Path] = f.Content
+			}
+		}
+		for _, f := range op.After.Proposal.Files {
+			cur, ok := before[f.Path]
+			if !ok {
+				p.value(f.Path, p.colored(colorGreen, "new file"))
+				continue
+			}
+			added, removed := lineChanges(cur, f.Content)
+			p.value(f.Path, fmt.Sprintf("%s %s", p.colored(colorGreen, fmt.Sprintf("+%d", added)), p.colored(colorRed, fmt.Sprintf("-%d", removed))))
+		}
+	default:
+		p.header("~", colorYellow, string(op.Kind), op.String())
+	}
+}
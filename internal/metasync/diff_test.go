@@ -0,0 +1,97 @@
+package metasync
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pmalek/github-pm-groomer/internal/plan"
+)
+
+func TestDiff(t *testing.T) {
+	fixture := `
+repos:
+  acme/widgets:
+    labels:
+      - {name: kind/bug, color: ff0000, description: Old}
+      - {name: wontfix, color: ffffff}
+    milestones:
+      - {number: 1, title: v1.0, state: open}
+`
+	tests := []struct {
+		name      string
+		conf      string
+		color     bool
+		want      string
+		wantDrift bool
+	}{
+		{
+			name: "in sync",
+			conf: `
+repos: [acme/widgets]
+config:
+  labels:
+    - {name: kind/bug, color: ff0000, description: Old}
+`,
+			want: "No changes, repositories match the configuration.\n",
+		},
+		{
+			name: "drift",
+			conf: `
+repos: [acme/widgets]
+config:
+  labels:
+    - {name: kind/bug, color: d73a4a, description: Something is broken}
+    - {name: kind/feature, color: a2eeef}
+    - {name: wontfix, delete: true}
+  milestones:
+    - {title: v1.0, closed: true}
+`,
+			want: `acme/widgets
+  ~ label "kind/bug"
+      color:          ff0000 → d73a4a
+      description:    "Old" → "Something is broken"
+  + label "kind/feature"
+      color:          a2eeef
+      description:    ""
+  - label "wontfix"
+  ~ milestone "v1.0"
+      state:          open → closed
+
+Summary
+REPO          CREATE  UPDATE  DELETE
+acme/widgets  1       2       1
+`,
+			wantDrift: true,
+		},
+		{
+			name: "colored",
+			conf: `
+repos: [acme/widgets]
+config:
+  labels:
+    - {name: wontfix, delete: true}
+`,
+			color:     true,
+			want:      "acme/widgets\n  \033[31m-\033[0m label \"wontfix\"\n\nSummary\nREPO          CREATE  UPDATE  DELETE\nacme/widgets  0       0       1\n",
+			wantDrift: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planner := plan.NewClient(newFake(t, fixture))
+			opts := Opts{FilePath: writeConf(t, tt.conf), Concurrency: 1}
+			if err := Run(context.Background(), planner, opts, testNow); err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			drift := Diff(&b, planner.Plan("", testNow).Operations, tt.color)
+			if drift != tt.wantDrift {
+				t.Errorf("got drift %v, want %v", drift, tt.wantDrift)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got diff\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package metasync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+type ExportOpts struct {
+	Repos []string
+	// Orgs exports all the repositories of each organization, except archived ones.
+	Orgs []string
+}
+
+func (o ExportOpts) Validate() error {
+	if len(o.Repos) == 0 && len(o.Orgs) == 0 {
+		return errors.New("at least one repo or org to export is required")
+	}
+	for _, repo := range o.Repos {
+		if _, _, err := utils.OrgRepo(repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export builds the configuration matching the current labels and milestones of the repositories. What all of
+// them share goes to config, the rest to each repository.
+func Export(ctx context.Context, client api.Client, opts ExportOpts) (ConfRoot, error) {
+	repos := append([]string{}, opts.Repos...)
+	for _, org := range opts.Orgs {
+		orgRepos, err := client.ListRepositories(ctx, org)
+		if err != nil {
+			return ConfRoot{}, err
+		}
+		for _, r := range orgRepos {
+			if r.GetArchived() {
+				slog.LogAttrs(ctx, slog.LevelInfo, "skipping archived repo", slog.String("repo", r.GetFullName()))
+				continue
+			}
+			if !slices.Contains(repos, r.GetFullName()) {
+				repos = append(repos, r.GetFullName())
+			}
+		}
+	}
+
+	confs := make([]Conf, len(repos))
+	for i, repo := range repos {
+		slog.LogAttrs(ctx, slog.LevelInfo, "exporting", slog.String("repo", repo))
+		labels, err := client.ListLabels(ctx, repo)
+		if err != nil {
+			return ConfRoot{}, err
+		}
+		for _, l := range labels {
+			confs[i].Labels = append(confs[i].Labels, LabelDef{
+				Name:        l.GetName(),
+				Color:       strings.ToLower(l.GetColor()),
+				Description: l.GetDescription(),
+			})
+		}
+		milestones, err := client.ListMilestones(ctx, repo)
+		if err != nil {
+			return ConfRoot{}, err
+		}
+		for _, m := range milestones {
+			def := MilestoneDef{
+				Title:       m.GetTitle(),
+				Closed:      m.GetState() == "closed",
+				Description: m.GetDescription(),
+			}
+			if m.DueOn != nil {
+				def.DueDate = Time{Time: m.DueOn.Time}
+			}
+			confs[i].Milestones = append(confs[i].Milestones, def)
+		}
+		sort.Slice(confs[i].Labels, func(a, b int) bool { return confs[i].Labels[a].Name < confs[i].Labels[b].Name })
+		sort.Slice(confs[i].Milestones, func(a, b int) bool { return confs[i].Milestones[a].Title < confs[i].Milestones[b].Title })
+	}
+
+	res := ConfRoot{}
+	labelKey := func(l LabelDef) string { return strings.Join([]string{l.Name, l.Color, l.Description}, "\x00") }
+	milestoneKey := func(m MilestoneDef) string {
+		closed := ""
+		if m.Closed {
+			closed = "closed"
+		}
+		return strings.Join([]string{m.Title, closed, m.Description, m.DueDate.Format(time.DateOnly)}, "\x00")
+	}
+	sharedLabels := shared(confs, func(c Conf) []LabelDef { return c.Labels }, labelKey)
+	sharedMilestones := shared(confs, func(c Conf) []MilestoneDef { return c.Milestones }, milestoneKey)
+	for i, repo := range repos {
+		def := RepoDef{Name: repo}
+		for _, l := range confs[i].Labels {
+			if sharedLabels[labelKey(l)] {
+				if i == 0 {
+					res.Config.Labels = append(res.Config.Labels, l)
+				}
+				continue
+			}
+			def.Labels = append(def.Labels, l)
+		}
+		for _, m := range confs[i].Milestones {
+			if sharedMilestones[milestoneKey(m)] {
+				if i == 0 {
+					res.Config.Milestones = append(res.Config.Milestones, m)
+				}
+				continue
+			}
+			def.Milestones = append(def.Milestones, m)
+		}
+		res.Repos = append(res.Repos, def)
+	}
+	return res, nil
+}
+
+// shared returns the keys of the items which all the configurations have.
+func shared[T any](confs []Conf, items func(Conf) []T, key func(T) string) map[string]bool {
+	counts := map[string]int{}
+	for _, c := range confs {
+		for _, item := range items(c) {
+			counts[key(item)] += 1
+		}
+	}
+	res := map[string]bool{}
+	for k, n := range counts {
+		if n == len(confs) {
+			res[k] = true
+		}
+	}
+	return res
+}
+
+// WriteConf writes the configuration as YAML.
+func WriteConf(w io.Writer, conf ConfRoot) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(conf); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,161 @@
+package metasync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+// filesBranch is the branch the pull requests proposing the managed files are opened from.
+const filesBranch = "meta-sync/files"
+
+// FileDef is a file kept identical across the repositories, changes to it are proposed with a pull request.
+type FileDef struct {
+	// Path is the path of the file in the repository, e.g. `.github/CODEOWNERS`.
+	Path    string `yaml:"path"`
+	Content string `yaml:"content,omitempty"`
+	// Source is a file or URL holding the content, relative paths are relative to the configuration file.
+	Source string `yaml:"source,omitempty"`
+	// Template renders the content as a Go template with .Repo (`org/repo`), .Org and .Name set.
+	Template bool `yaml:"template,omitempty"`
+
+	pos position
+}
+
+func (f *FileDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain FileDef
+	if err := value.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	f.pos = positionOf(value)
+	return nil
+}
+
+type fileData struct {
+	Repo string
+	Org  string
+	Name string
+}
+
+// fileSources caches the content of the sources so each one is read once per run.
+type fileSources map[string][]byte
+
+// content returns what the file has to hold in repo.
+func (f FileDef) content(sources fileSources, repo string) (string, error) {
+	content := f.Content
+	if f.Source != "" {
+		source, err := resolveRelative(f.pos.file, f.Source)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := sources[source]; !ok {
+			b, err := readSource(source)
+			if err != nil {
+				return "", fmt.Errorf("file %s: %w", f.Path, err)
+			}
+			sources[source] = b
+		}
+		content = string(sources[source])
+	}
+	if !f.Template {
+		return content, nil
+	}
+	tmpl, err := template.New(f.Path).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", err
+	}
+	org, name, err := utils.OrgRepo(repo)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, fileData{Repo: repo, Org: org, Name: name}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// syncFiles proposes the files, issue forms included, which differ from the default branch in a single pull
+// request.
+func syncFiles(ctx context.Context, client api.Client, repo string, conf Conf, sources fileSources) error {
+	forms, err := conf.issueFormFiles()
+	if err != nil {
+		return fmt.Errorf("%s: %w", repo, err)
+	}
+	files := append(append([]FileDef{}, conf.Files...), forms...)
+	if len(files) == 0 {
+		return nil
+	}
+	paths := map[string]bool{}
+	for _, def := range files {
+		if paths[def.Path] {
+			return fmt.Errorf("%s: %s is both a file and an issue form", repo, def.Path)
+		}
+		paths[def.Path] = true
+	}
+	logger := slog.With(slog.String("repo", repo))
+	logger.LogAttrs(ctx, slog.LevelInfo, "sync files")
+
+	proposal := &api.FileProposal{
+		Branch:  filesBranch,
+		Message: "Sync files managed by meta-sync",
+		Title:   "Sync files managed by meta-sync",
+	}
+	var changed []string
+	for _, def := range files {
+		want, err := def.content(sources, repo)
+		if err != nil {
+			return err
+		}
+		cur, err := client.GetFile(ctx, repo, def.Path, "")
+		if err != nil {
+			return err
+		}
+		if cur != nil && string(cur.Content) == want {
+			continue
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "file differs", slog.String("path", def.Path), slog.Bool("exists", cur != nil))
+		proposal.Files = append(proposal.Files, api.File{Path: def.Path, Content: []byte(want)})
+		changed = append(changed, "- `"+def.Path+"`")
+	}
+	if len(proposal.Files) == 0 {
+		return nil
+	}
+	proposal.Body = "These files are managed by meta-sync and differ from its configuration:\n\n" + strings.Join(changed, "\n") + "\n"
+	logger.LogAttrs(ctx, slog.LevelInfo, "proposing files", slog.Int("files", len(proposal.Files)))
+	return client.ProposeFiles(ctx, repo, proposal)
+}
+
+func (v *validator) files(defs []FileDef, scope string) {
+	paths := map[string]FileDef{}
+	for _, def := range defs {
+		if def.Path == "" {
+			v.report(def.pos, "file of %s has no path", scope)
+			continue
+		}
+		if prev, ok := paths[def.Path]; ok {
+			v.report(def.pos, "file %q of %s is already defined at line %d", def.Path, scope, prev.pos.line)
+		} else {
+			paths[def.Path] = def
+		}
+		if path.IsAbs(def.Path) || path.Clean(def.Path) != def.Path || def.Path == ".." || strings.HasPrefix(def.Path, "../") {
+			v.report(def.pos, "file %q must be a clean path relative to the repository root", def.Path)
+		}
+		if (def.Content == "") == (def.Source == "") {
+			v.report(def.pos, "file %q needs exactly one of content or source", def.Path)
+		}
+		if def.Template && def.Content != "" {
+			if _, err := template.New(def.Path).Parse(def.Content); err != nil {
+				v.report(def.pos, "file %q has an invalid template: %s", def.Path, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package metasync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+)
+
+const filesFixture = `
+repos:
+  acme/widgets:
+    files:
+      CODEOWNERS: "* @acme/old"
+`
+
+func filesConf(owners string) string {
+	return `
+repos: [acme/widgets]
+config:
+  files:
+    - {path: CODEOWNERS, content: "` + owners + `"}
+`
+}
+
+// pullRequests lists the pull requests of acme/widgets as `branch state`.
+func pullRequests(t *testing.T, client *fake.Client) []string {
+	t.Helper()
+	prs, err := client.PullRequests("acme/widgets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, pr := range prs {
+		res = append(res, pr.Branch+" "+pr.State)
+	}
+	return res
+}
+
+func TestRunFiles(t *testing.T) {
+	client := newFake(t, filesFixture)
+	steps := []struct {
+		name string
+		// before changes the repository before running.
+		before     func() error
+		owners     string
+		wantPRs    []string
+		wantBranch map[string]string
+	}{
+		{
+			name:       "proposed",
+			owners:     "* @acme/new",
+			wantPRs:    []string{"meta-sync/files open"},
+			wantBranch: map[string]string{"CODEOWNERS": "* @acme/new"},
+		},
+		{
+			name:       "open pull request updated",
+			owners:     "* @acme/newer",
+			wantPRs:    []string{"meta-sync/files open"},
+			wantBranch: map[string]string{"CODEOWNERS": "* @acme/newer"},
+		},
+		{
+			name:       "closed pull request not proposed again",
+			before:     func() error { return client.ClosePullRequest("acme/widgets", filesBranch, false) },
+			owners:     "* @acme/newer",
+			wantPRs:    []string{"meta-sync/files closed"},
+			wantBranch: map[string]string{"CODEOWNERS": "* @acme/newer"},
+		},
+		{
+			name:    "changed files proposed from a reset branch",
+			before:  func() error { return client.SetFile("acme/widgets", "main", "README.md", "# Widgets") },
+			owners:  "* @acme/newest",
+			wantPRs: []string{"meta-sync/files closed", "meta-sync/files open"},
+			wantBranch: map[string]string{
+				"CODEOWNERS": "* @acme/newest",
+				"README.md":  "# Widgets",
+			},
+		},
+		{
+			name:    "nothing proposed once merged",
+			before:  func() error { return client.ClosePullRequest("acme/widgets", filesBranch, true) },
+			owners:  "* @acme/newest",
+			wantPRs: []string{"meta-sync/files closed", "meta-sync/files merged"},
+			wantBranch: map[string]string{
+				"CODEOWNERS": "* @acme/newest",
+				"README.md":  "# Widgets",
+			},
+		},
+		{
+			name:    "proposed again after the merge from a reset branch",
+			before:  func() error { return client.SetFile("acme/widgets", "main", "README.md", "# Widgets!") },
+			owners:  "* @acme/all",
+			wantPRs: []string{"meta-sync/files closed", "meta-sync/files merged", "meta-sync/files open"},
+			wantBranch: map[string]string{
+				"CODEOWNERS": "* @acme/all",
+				"README.md":  "# Widgets!",
+			},
+		},
+	}
+	for _, step := range steps {
+		if step.before != nil {
+			if err := step.before(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := run(t, client, filesConf(step.owners), Opts{}); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := pullRequests(t, client); !slices.Equal(got, step.wantPRs) {
+			t.Errorf("%s: pull requests are %q, want %q", step.name, got, step.wantPRs)
+		}
+		for path, want := range step.wantBranch {
+			f, err := client.GetFile(context.Background(), "acme/widgets", path, filesBranch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f == nil || string(f.Content) != want {
+				t.Errorf("%s: %s on the branch is %v, want %q", step.name, path, f, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package metasync
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+// issueTemplateDir is where GitHub looks for issue forms.
+const issueTemplateDir = ".github/ISSUE_TEMPLATE"
+
+// IssueFormDef is an issue form generated into issueTemplateDir, its dropdowns list the labels of the repository
+// having a prefix so the form and the labels can't diverge. It's synced like the other files.
+type IssueFormDef struct {
+	// File is the name of the form in issueTemplateDir, e.g. `bug.yml`.
+	File        string `yaml:"file"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Title       string `yaml:"title,omitempty"`
+	// Labels are added to the issues created with the form.
+	Labels    []string           `yaml:"labels,omitempty"`
+	Assignees []string           `yaml:"assignees,omitempty"`
+	Dropdowns []LabelDropdownDef `yaml:"dropdowns,omitempty"`
+	// Body are form elements added after the dropdowns as they are, see
+	// https://docs.github.com/en/communities/using-templates-to-encourage-useful-issues-and-pull-requests/syntax-for-githubs-form-schema
+	Body []yaml.Node `yaml:"body,omitempty"`
+
+	pos position
+}
+
+func (f *IssueFormDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain IssueFormDef
+	if err := value.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	f.pos = positionOf(value)
+	return nil
+}
+
+// LabelDropdownDef is a dropdown with an option per label having Prefix, e.g. `area/`. Dropdown options can't have
+// a description so the descriptions of the labels are listed in the one of the dropdown.
+type LabelDropdownDef struct {
+	Prefix string `yaml:"prefix"`
+	// Label defaults to the prefix without its separator, capitalized.
+	Label       string `yaml:"label,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Multiple    bool   `yaml:"multiple,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+var nonIDRe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+func (d LabelDropdownDef) id() string {
+	return strings.Trim(nonIDRe.ReplaceAllString(strings.ToLower(d.Prefix), "-"), "-")
+}
+
+type issueForm struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Title       string   `yaml:"title,omitempty"`
+	Labels      []string `yaml:"labels,omitempty"`
+	Assignees   []string `yaml:"assignees,omitempty"`
+	Body        []any    `yaml:"body"`
+}
+
+type dropdown struct {
+	Type        string              `yaml:"type"`
+	ID          string              `yaml:"id"`
+	Attributes  dropdownAttributes  `yaml:"attributes"`
+	Validations dropdownValidations `yaml:"validations"`
+}
+
+type dropdownAttributes struct {
+	Label       string   `yaml:"label"`
+	Description string   `yaml:"description,omitempty"`
+	Multiple    bool     `yaml:"multiple"`
+	Options     []string `yaml:"options"`
+}
+
+type dropdownValidations struct {
+	Required bool `yaml:"required"`
+}
+
+// issueFormFiles generates the issue forms of conf from its labels.
+func (c Conf) issueFormFiles() ([]FileDef, error) {
+	var res []FileDef
+	for _, def := range c.IssueForms {
+		form := issueForm{
+			Name:        def.Name,
+			Description: def.Description,
+			Title:       def.Title,
+			Labels:      def.Labels,
+			Assignees:   def.Assignees,
+		}
+		for _, d := range def.Dropdowns {
+			dd := dropdown{
+				Type:        "dropdown",
+				ID:          d.id(),
+				Attributes:  dropdownAttributes{Label: d.Label, Multiple: d.Multiple},
+				Validations: dropdownValidations{Required: d.Required},
+			}
+			if dd.Attributes.Label == "" {
+				dd.Attributes.Label = strings.ToUpper(dd.ID[:1]) + dd.ID[1:]
+			}
+			var help []string
+			for _, l := range c.Labels {
+				if l.Delete || !strings.HasPrefix(l.Name, d.Prefix) {
+					continue
+				}
+				dd.Attributes.Options = append(dd.Attributes.Options, l.Name)
+				if l.Description != "" {
+					help = append(help, fmt.Sprintf("- `%s`: %s", l.Name, l.Description))
+				}
+			}
+			if len(dd.Attributes.Options) == 0 {
+				return nil, fmt.Errorf("issue form %s: no label has the prefix %q", def.File, d.Prefix)
+			}
+			dd.Attributes.Description = strings.TrimSpace(d.Description + "\n\n" + strings.Join(help, "\n"))
+			form.Body = append(form.Body, dd)
+		}
+		for i := range def.Body {
+			form.Body = append(form.Body, blockStyle(&def.Body[i]))
+		}
+
+		var b bytes.Buffer
+		b.WriteString("# Generated by meta-sync from the labels of the repository, edit its configuration instead.\n")
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(form); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		res = append(res, FileDef{Path: path.Join(issueTemplateDir, def.File), Content: b.String(), pos: def.pos})
+	}
+	return res, nil
+}
+
+// blockStyle returns a copy of node where the mappings and sequences written inline in the configuration are
+// written as blocks, like the rest of the form.
+func blockStyle(node *yaml.Node) *yaml.Node {
+	res := *node
+	if res.Kind == yaml.MappingNode || res.Kind == yaml.SequenceNode {
+		res.Style &^= yaml.FlowStyle
+	}
+	res.Content = make([]*yaml.Node, 0, len(node.Content))
+	for _, c := range node.Content {
+		res.Content = append(res.Content, blockStyle(c))
+	}
+	return &res
+}
+
+// IssueForms generates the issue forms of repo from the configuration at path, without calling GitHub.
+func IssueForms(path string, repo string) ([]api.File, error) {
+	conf, err := parseConf(path)
+	if err != nil {
+		return nil, err
+	}
+	if diagnostics := conf.validate(); len(diagnostics) > 0 {
+		return nil, invalidConfError(diagnostics)
+	}
+	def := RepoDef{Name: repo}
+	for _, r := range conf.Repos {
+		if strings.EqualFold(r.Name, repo) {
+			def = r
+		}
+	}
+	files, err := conf.Resolve(def).issueFormFiles()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]api.File, 0, len(files))
+	for _, f := range files {
+		res = append(res, api.File{Path: f.Path, Content: []byte(f.Content)})
+	}
+	return res, nil
+}
+
+func (v *validator) issueForms(defs []IssueFormDef, scope string) {
+	files := map[string]IssueFormDef{}
+	for _, def := range defs {
+		if def.File == "" {
+			v.report(def.pos, "issue form of %s has no file", scope)
+			continue
+		}
+		if prev, ok := files[def.File]; ok {
+			v.report(def.pos, "issue form %q of %s is already defined at line %d", def.File, scope, prev.pos.line)
+		} else {
+			files[def.File] = def
+		}
+		if strings.Contains(def.File, "/") || !(strings.HasSuffix(def.File, ".yml") || strings.HasSuffix(def.File, ".yaml")) {
+			v.report(def.pos, "issue form %q must be a .yml or .yaml file name", def.File)
+		}
+		if def.Name == "" || def.Description == "" {
+			v.report(def.pos, "issue form %q needs a name and a description", def.File)
+		}
+		if len(def.Dropdowns) == 0 && len(def.Body) == 0 {
+			v.report(def.pos, "issue form %q has no dropdowns nor body", def.File)
+		}
+		for _, d := range def.Dropdowns {
+			if d.id() == "" {
+				v.report(def.pos, "issue form %q has a dropdown without a prefix", def.File)
+			}
+		}
+	}
+}
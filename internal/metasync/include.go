@@ -0,0 +1,271 @@
+package metasync
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// confLoader reads a configuration and its includes, merging them into a single ConfRoot. The same repository,
+// label, milestone or group can only be defined once across all the files.
+type confLoader struct {
+	visiting map[string]bool
+	// loaded are the sources already merged, a source included from several files is merged once.
+	loaded map[string]bool
+	// origins maps what was defined to the file defining it.
+	origins map[string]string
+}
+
+func (l *confLoader) load(into *ConfRoot, source string) error {
+	if l.visiting[source] {
+		return fmt.Errorf("%s: include cycle", source)
+	}
+	if l.loaded[source] {
+		return nil
+	}
+	l.visiting[source] = true
+	l.loaded[source] = true
+	defer delete(l.visiting, source)
+
+	b, err := readSource(source)
+	if err != nil {
+		return err
+	}
+	conf := ConfRoot{}
+	if err := yaml.Unmarshal(b, &conf); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if err := l.merge(into, conf, source); err != nil {
+		return err
+	}
+
+	for _, include := range conf.Include {
+		paths, err := resolveInclude(source, include)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			if err := l.load(into, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRelative resolves ref, a path or a URL, relative to the file or URL source.
+func resolveRelative(source string, ref string) (string, error) {
+	if isURL(source) && !filepath.IsAbs(ref) {
+		base, err := url.Parse(source)
+		if err != nil {
+			return "", err
+		}
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(u).String(), nil
+	}
+	if isURL(ref) || filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	return filepath.Join(filepath.Dir(source), ref), nil
+}
+
+// resolveInclude returns the sources an include of source points to, directories are expanded to their YAML files.
+func resolveInclude(source string, include string) ([]string, error) {
+	include, err := resolveRelative(source, include)
+	if err != nil {
+		return nil, err
+	}
+	if isURL(include) {
+		return []string{include}, nil
+	}
+	stat, err := os.Stat(include)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if !stat.IsDir() {
+		return []string{include}, nil
+	}
+	entries, err := os.ReadDir(include)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, e := range entries {
+		if !e.IsDir() && (strings.HasSuffix(e.Name(), ".yaml") || strings.HasSuffix(e.Name(), ".yml")) {
+			res = append(res, filepath.Join(include, e.Name()))
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// define fails when what was already defined in another file, duplicates within a file are diagnosed when
+// validating as they can be pinpointed.
+func (l *confLoader) define(what string, source string) error {
+	if prev, ok := l.origins[what]; ok && prev != source {
+		return fmt.Errorf("%s is defined in both %s and %s", what, prev, source)
+	}
+	l.origins[what] = source
+	return nil
+}
+
+func (l *confLoader) mergeLayer(into *Layer, from Layer, scope string, source string) error {
+	from.setFile(source)
+	for _, def := range from.Labels {
+		// Label names are case-insensitive on GitHub.
+		if err := l.define(fmt.Sprintf("label %q of %s", strings.ToLower(def.Name), scope), source); err != nil {
+			return err
+		}
+		into.Labels = append(into.Labels, def)
+	}
+	for _, def := range from.LabelGroups {
+		if err := l.define(fmt.Sprintf("label group %q of %s", def.Prefix, scope), source); err != nil {
+			return err
+		}
+		into.LabelGroups = append(into.LabelGroups, def)
+	}
+	for _, def := range from.Milestones {
+		if err := l.define(fmt.Sprintf("milestone %q of %s", def.Title, scope), source); err != nil {
+			return err
+		}
+		into.Milestones = append(into.Milestones, def)
+	}
+	for _, def := range from.MilestoneSchedules {
+		if err := l.define(fmt.Sprintf("milestone schedule %q of %s", def.Title, scope), source); err != nil {
+			return err
+		}
+		into.MilestoneSchedules = append(into.MilestoneSchedules, def)
+	}
+	for _, def := range from.BranchProtection {
+		if err := l.define(fmt.Sprintf("branch protection %q of %s", def.Pattern, scope), source); err != nil {
+			return err
+		}
+		into.BranchProtection = append(into.BranchProtection, def)
+	}
+	for _, def := range from.Files {
+		if err := l.define(fmt.Sprintf("file %q of %s", def.Path, scope), source); err != nil {
+			return err
+		}
+		into.Files = append(into.Files, def)
+	}
+	for _, def := range from.IssueForms {
+		if err := l.define(fmt.Sprintf("issue form %q of %s", def.File, scope), source); err != nil {
+			return err
+		}
+		into.IssueForms = append(into.IssueForms, def)
+	}
+	if from.Settings != nil {
+		if err := l.define(fmt.Sprintf("settings of %s", scope), source); err != nil {
+			return err
+		}
+		into.Settings = from.Settings
+	}
+	if from.Access != nil {
+		if err := l.define(fmt.Sprintf("access of %s", scope), source); err != nil {
+			return err
+		}
+		into.Access = from.Access
+	}
+	into.Exclude.Labels = append(into.Exclude.Labels, from.Exclude.Labels...)
+	into.Exclude.Milestones = append(into.Exclude.Milestones, from.Exclude.Milestones...)
+	into.Exclude.BranchProtection = append(into.Exclude.BranchProtection, from.Exclude.BranchProtection...)
+	into.Exclude.Files = append(into.Exclude.Files, from.Exclude.Files...)
+	into.Exclude.IssueForms = append(into.Exclude.IssueForms, from.Exclude.IssueForms...)
+	return nil
+}
+
+// setFile records source as the file defining everything in the layer.
+func (l *Layer) setFile(source string) {
+	for i := range l.Labels {
+		l.Labels[i].pos.file = source
+	}
+	for i := range l.LabelGroups {
+		l.LabelGroups[i].setFile(source)
+	}
+	for i := range l.Milestones {
+		l.Milestones[i].pos.file = source
+	}
+	for i := range l.MilestoneSchedules {
+		l.MilestoneSchedules[i].pos.file = source
+	}
+	if l.Settings != nil {
+		l.Settings.pos.file = source
+	}
+	for i := range l.BranchProtection {
+		l.BranchProtection[i].pos.file = source
+	}
+	if l.Access != nil {
+		l.Access.pos.file = source
+	}
+	for i := range l.Files {
+		l.Files[i].pos.file = source
+	}
+	for i := range l.IssueForms {
+		l.IssueForms[i].pos.file = source
+	}
+}
+
+func (l *confLoader) merge(into *ConfRoot, from ConfRoot, source string) error {
+	for _, repo := range from.Repos {
+		if err := l.define(fmt.Sprintf("repo %q", repo.Name), source); err != nil {
+			return err
+		}
+		repo.pos.file = source
+		repo.setFile(source)
+		into.Repos = append(into.Repos, repo)
+	}
+
+	config := Layer{Conf: into.Config}
+	if err := l.mergeLayer(&config, Layer{Conf: from.Config}, "config", source); err != nil {
+		return err
+	}
+	into.Config = config.Conf
+
+	for org, layer := range from.Orgs {
+		if into.Orgs == nil {
+			into.Orgs = map[string]Layer{}
+		}
+		merged := into.Orgs[org]
+		if err := l.mergeLayer(&merged, layer, fmt.Sprintf("org %q", org), source); err != nil {
+			return err
+		}
+		into.Orgs[org] = merged
+	}
+
+	for name, group := range from.Groups {
+		if err := l.define(fmt.Sprintf("group %q", name), source); err != nil {
+			return err
+		}
+		if into.Groups == nil {
+			into.Groups = map[string]Group{}
+		}
+		group.setFile(source)
+		into.Groups[name] = group
+	}
+
+	if !from.Targets.isZero() {
+		if err := l.define("targets", source); err != nil {
+			return err
+		}
+		into.Targets = from.Targets
+		for i := range into.Targets.Exclude {
+			into.Targets.Exclude[i].pos.file = source
+		}
+	}
+
+	if !from.Prune.isZero() {
+		if err := l.define("prune", source); err != nil {
+			return err
+		}
+		into.Prune = from.Prune
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package metasync
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseConfIncludes(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantErr    string
+		wantRepos  []string
+		wantLabels []string
+	}{
+		{
+			name: "files and directories",
+			files: map[string]string{
+				"meta-sync.yaml":  "include: [labels.yaml, repos]\nrepos: [acme/api]\n",
+				"labels.yaml":     "config:\n  labels: [{name: bug, color: d73a4a}]\n",
+				"repos/web.yaml":  "repos: [acme/web]\n",
+				"repos/lib.yml":   "repos: [acme/lib]\n",
+				"repos/notes.txt": "not yaml",
+			},
+			wantRepos:  []string{"acme/api", "acme/lib", "acme/web"},
+			wantLabels: []string{"bug"},
+		},
+		{
+			name: "diamond",
+			files: map[string]string{
+				"meta-sync.yaml": "include: [b.yaml, c.yaml]\n",
+				"b.yaml":         "include: [d.yaml]\nrepos: [acme/b]\n",
+				"c.yaml":         "include: [./d.yaml]\nrepos: [acme/c]\n",
+				"d.yaml":         "repos: [acme/d]\nconfig:\n  labels: [{name: bug, color: d73a4a}]\n",
+			},
+			wantRepos:  []string{"acme/b", "acme/c", "acme/d"},
+			wantLabels: []string{"bug"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"meta-sync.yaml": "include: [a.yaml]\n",
+				"a.yaml":         "include: [meta-sync.yaml]\n",
+			},
+			wantErr: "include cycle",
+		},
+		{
+			name: "defined twice",
+			files: map[string]string{
+				"meta-sync.yaml": "include: [a.yaml]\nconfig:\n  labels: [{name: bug, color: d73a4a}]\n",
+				"a.yaml":         "config:\n  labels: [{name: BUG, color: ff0000}]\n",
+			},
+			wantErr: `label "bug" of config is defined in both`,
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"meta-sync.yaml": "include: [missing.yaml]\n",
+			},
+			wantErr: "no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+			conf, err := parseConf(filepath.Join(dir, "meta-sync.yaml"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var repos, labels []string
+			for _, r := range conf.Repos {
+				repos = append(repos, r.Name)
+			}
+			for _, l := range conf.Config.Labels {
+				labels = append(labels, l.Name)
+			}
+			slices.Sort(repos)
+			if !slices.Equal(repos, tt.wantRepos) {
+				t.Errorf("repos are %q, want %q", repos, tt.wantRepos)
+			}
+			if !slices.Equal(labels, tt.wantLabels) {
+				t.Errorf("labels are %q, want %q", labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestParseConfPositions(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"meta-sync.yaml": "include: [groups.yaml, targets.yaml]\nrepos:\n  - name: acme/api\n    labels: [{name: bug, color: red}]\n",
+		"groups.yaml":    "groups:\n  web:\n    repos: [acme/web-*, \"acme/[web\", acme]\n    milestones:\n      - {title: v1, dueDate: soon}\n",
+		"targets.yaml":   "targets:\n  exclude:\n    - acme/legacy-*\n    - topic:legacy\n",
+	})
+	diagnostics, err := Validate(filepath.Join(dir, "meta-sync.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, strings.TrimPrefix(d.String(), dir+string(filepath.Separator)))
+	}
+	want := []string{
+		`groups.yaml:3:25: group web repos "acme/[web" is not a valid pattern`,
+		`groups.yaml:3:38: group web repos "acme" is not in the org/repo format`,
+		`groups.yaml:5:9: milestone "v1" has an invalid dueDate "soon", expected YYYY-MM-DD`,
+		`meta-sync.yaml:4:14: label "bug" has an invalid color "red", expected 6 hex digits without #`,
+		`targets.yaml:4:7: targets.exclude "topic:legacy" is not in the topic:org/topic format`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got diagnostics %q, want %q", got, want)
+	}
+}
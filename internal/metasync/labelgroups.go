@@ -0,0 +1,213 @@
+package metasync
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// goldenAngle spreads the hues of generated colors so consecutive members get distinct colors.
+const goldenAngle = 137.50776405
+
+// LabelGroupDef declares labels sharing a prefix and defaults, e.g. all the `area/` labels. Its members are expanded
+// into labels of the layer declaring it, so they can be overridden or excluded like any other label.
+type LabelGroupDef struct {
+	Prefix string `yaml:"prefix"`
+	// Color is the color of the members which don't set one, the base hue with AutoColors.
+	Color string `yaml:"color,omitempty"`
+	// Description is a Go template with .Name, the member name, and .Label, the label name, set.
+	Description string `yaml:"description,omitempty"`
+	// AutoColors gives each member without a color a distinct one, members keep theirs as long as new ones are
+	// added last.
+	AutoColors bool               `yaml:"autoColors,omitempty"`
+	Members    []LabelGroupMember `yaml:"members"`
+
+	pos position
+}
+
+func (g *LabelGroupDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain LabelGroupDef
+	if err := value.Decode((*plain)(g)); err != nil {
+		return err
+	}
+	g.pos = positionOf(value)
+	return nil
+}
+
+// LabelGroupMember is a label of a group, either just its name without the prefix or a mapping overriding the
+// defaults of the group.
+type LabelGroupMember struct {
+	Name        string   `yaml:"name"`
+	Color       string   `yaml:"color,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Previously  []string `yaml:"previously,omitempty"`
+
+	pos position
+}
+
+func (m *LabelGroupMember) UnmarshalYAML(value *yaml.Node) error {
+	m.pos = positionOf(value)
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&m.Name)
+	}
+	type plain LabelGroupMember
+	pos := m.pos
+	if err := value.Decode((*plain)(m)); err != nil {
+		return err
+	}
+	m.pos = pos
+	return nil
+}
+
+func (g *LabelGroupDef) setFile(source string) {
+	g.pos.file = source
+	for i := range g.Members {
+		g.Members[i].pos.file = source
+	}
+}
+
+type labelGroupData struct {
+	Name  string
+	Label string
+}
+
+func (g LabelGroupDef) describe(member string) (string, error) {
+	tmpl, err := template.New(g.Prefix).Option("missingkey=error").Parse(g.Description)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, labelGroupData{Name: member, Label: g.Prefix + member}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// autoColor is the i-th color of a group, hues are spread from the one of base.
+func autoColor(base string, i int) string {
+	hue := 0.0
+	if colorRe.MatchString(base) {
+		hue, _, _ = hsl(base)
+	}
+	return hslColor(math.Mod(hue+float64(i)*goldenAngle, 360), 0.6, 0.5)
+}
+
+func hsl(color string) (float64, float64, float64) {
+	var r, g, b int
+	_, _ = fmt.Sscanf(strings.ToLower(color), "%02x%02x%02x", &r, &g, &b)
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	maxC, minC := math.Max(rf, math.Max(gf, bf)), math.Min(rf, math.Min(gf, bf))
+	l := (maxC + minC) / 2
+	if maxC == minC {
+		return 0, 0, l
+	}
+	d := maxC - minC
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch maxC {
+	case rf:
+		h = math.Mod((gf-bf)/d, 6)
+	case gf:
+		h = (bf-rf)/d + 2
+	default:
+		h = (rf-gf)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+func hslColor(h float64, s float64, l float64) string {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	scale := func(v float64) int { return int(math.Round((v + m) * 255)) }
+	return fmt.Sprintf("%02x%02x%02x", scale(r), scale(g), scale(b))
+}
+
+// labels expands the group into labels, a description which can't be rendered is left as is, it's reported when
+// validating.
+func (g LabelGroupDef) labels() []LabelDef {
+	res := make([]LabelDef, 0, len(g.Members))
+	for i, m := range g.Members {
+		if m.Name == "" {
+			continue
+		}
+		def := LabelDef{Name: g.Prefix + m.Name, Color: m.Color, Description: m.Description, Previously: m.Previously, pos: m.pos}
+		if def.Color == "" {
+			def.Color = g.Color
+			if g.AutoColors {
+				def.Color = autoColor(g.Color, i)
+			}
+		}
+		if def.Description == "" && g.Description != "" {
+			description, err := g.describe(m.Name)
+			if err != nil {
+				description = g.Description
+			}
+			def.Description = description
+		}
+		res = append(res, def)
+	}
+	return res
+}
+
+// labels returns the labels of conf followed by those of its label groups.
+func (c Conf) labels() []LabelDef {
+	res := append([]LabelDef{}, c.Labels...)
+	for _, g := range c.LabelGroups {
+		res = append(res, g.labels()...)
+	}
+	return res
+}
+
+func (v *validator) labelGroups(defs []LabelGroupDef, scope string) {
+	prefixes := map[string]LabelGroupDef{}
+	for _, def := range defs {
+		if def.Prefix == "" {
+			v.report(def.pos, "label group of %s has no prefix", scope)
+			continue
+		}
+		if prev, ok := prefixes[def.Prefix]; ok {
+			v.report(def.pos, "label group %q of %s is already defined at line %d", def.Prefix, scope, prev.pos.line)
+		} else {
+			prefixes[def.Prefix] = def
+		}
+		if def.Color != "" && !colorRe.MatchString(def.Color) {
+			v.report(def.pos, "label group %q has an invalid color %q, expected 6 hex digits without #", def.Prefix, def.Color)
+		}
+		if def.Description != "" {
+			if _, err := def.describe("member"); err != nil {
+				v.report(def.pos, "label group %q has an invalid description template: %s", def.Prefix, err)
+			}
+		}
+		if len(def.Members) == 0 {
+			v.report(def.pos, "label group %q has no members", def.Prefix)
+		}
+		for _, m := range def.Members {
+			if m.Name == "" {
+				v.report(m.pos, "member of label group %q has no name", def.Prefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package metasync
+
+import "testing"
+
+func TestAutoColor(t *testing.T) {
+	tests := []struct {
+		base string
+		i    int
+		want string
+	}{
+		{base: "ff0000", i: 0, want: "cc3333"},
+		{base: "ff0000", i: 1, want: "33cc60"},
+		{base: "ff0000", i: 2, want: "8c33cc"},
+		{base: "0000FF", i: 0, want: "3333cc"},
+		{base: "0000ff", i: 1, want: "cc6033"},
+		// Without a valid base the hues start from red.
+		{base: "", i: 1, want: "33cc60"},
+		{base: "red", i: 2, want: "8c33cc"},
+	}
+	for _, tt := range tests {
+		if got := autoColor(tt.base, tt.i); got != tt.want {
+			t.Errorf("autoColor(%q, %d) = %q, want %q", tt.base, tt.i, got, tt.want)
+		}
+	}
+
+	seen := map[string]int{}
+	for i := range 32 {
+		color := autoColor("d73a4a", i)
+		if !colorRe.MatchString(color) {
+			t.Fatalf("autoColor(%d) = %q, not a color", i, color)
+		}
+		if prev, ok := seen[color]; ok {
+			t.Errorf("members %d and %d both get %q", prev, i, color)
+		}
+		seen[color] = i
+	}
+}
+
+func TestHSL(t *testing.T) {
+	for _, color := range []string{"d73a4a", "0075ca", "a2eeef", "7057ff", "ffffff", "000000", "808080"} {
+		if got := hslColor(hsl(color)); got != color {
+			t.Errorf("hslColor(hsl(%q)) = %q", color, got)
+		}
+	}
+}
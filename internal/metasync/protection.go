@@ -0,0 +1,198 @@
+package metasync
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/mutation"
+)
+
+// rulesetPrefix names the rulesets managed by meta-sync, the other rulesets of a repository are left alone.
+const rulesetPrefix = "meta-sync: "
+
+// maxRequiredReviews is the most approvals GitHub can require.
+const maxRequiredReviews = 10
+
+// bypassRoles are the actors of the roles which can bypass a ruleset, repository roles have fixed IDs.
+var bypassRoles = map[string]mutation.BypassActor{
+	"orgAdmin": {Type: "OrganizationAdmin", ID: 1, Mode: "always"},
+	"admin":    {Type: "RepositoryRole", ID: 5, Mode: "always"},
+	"maintain": {Type: "RepositoryRole", ID: 2, Mode: "always"},
+	"write":    {Type: "RepositoryRole", ID: 4, Mode: "always"},
+}
+
+// bypassActor parses an entry of BranchProtectionDef.Bypass.
+func bypassActor(name string) (mutation.BypassActor, bool) {
+	if id, ok := strings.CutPrefix(name, "app:"); ok {
+		n, err := strconv.ParseInt(id, 10, 64)
+		return mutation.BypassActor{Type: "Integration", ID: n, Mode: "always"}, err == nil && n > 0
+	}
+	actor, ok := bypassRoles[name]
+	return actor, ok
+}
+
+// BranchProtectionDef protects the branches matching a pattern, it's synced as a repository ruleset.
+type BranchProtectionDef struct {
+	// Pattern is a branch name or pattern (e.g. `release/*`), `~DEFAULT_BRANCH` is the default branch.
+	Pattern string `yaml:"pattern"`
+	// RequirePullRequest forbids pushing directly, implied by RequiredReviews.
+	RequirePullRequest bool `yaml:"requirePullRequest,omitempty"`
+	RequiredReviews    int  `yaml:"requiredReviews,omitempty"`
+	// DismissStaleReviews and RequireCodeOwnerReviews are ignored unless pull requests are required.
+	DismissStaleReviews     bool     `yaml:"dismissStaleReviews,omitempty"`
+	RequireCodeOwnerReviews bool     `yaml:"requireCodeOwnerReviews,omitempty"`
+	RequiredStatusChecks    []string `yaml:"requiredStatusChecks,omitempty"`
+	// StrictStatusChecks requires branches to be up to date before merging, ignored without RequiredStatusChecks.
+	StrictStatusChecks bool `yaml:"strictStatusChecks,omitempty"`
+	LinearHistory      bool `yaml:"linearHistory,omitempty"`
+	BlockForcePushes   bool `yaml:"blockForcePushes,omitempty"`
+	BlockDeletions     bool `yaml:"blockDeletions,omitempty"`
+	// RestrictUpdates only lets Bypass push, merge included.
+	RestrictUpdates bool `yaml:"restrictUpdates,omitempty"`
+	// Bypass are the roles (`orgAdmin`, `admin`, `maintain`, `write`) and apps (`app:<id>`) exempt from the rules.
+	Bypass []string `yaml:"bypass,omitempty"`
+
+	pos position
+}
+
+func (d *BranchProtectionDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain BranchProtectionDef
+	if err := value.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	d.pos = positionOf(value)
+	return nil
+}
+
+func (d BranchProtectionDef) ruleset() *mutation.Ruleset {
+	ref := d.Pattern
+	if !strings.HasPrefix(ref, "~") {
+		ref = "refs/heads/" + ref
+	}
+	actors := []mutation.BypassActor{}
+	for _, name := range d.Bypass {
+		if actor, ok := bypassActor(name); ok && !slices.Contains(actors, actor) {
+			actors = append(actors, actor)
+		}
+	}
+	mutation.SortBypassActors(actors)
+	// Options without their rule can't be set on GitHub, keeping them would update the ruleset on every sync.
+	requirePullRequest := d.RequirePullRequest || d.RequiredReviews > 0
+	requireStatusChecks := len(d.RequiredStatusChecks) > 0
+	return &mutation.Ruleset{
+		Name:                    rulesetPrefix + d.Pattern,
+		Enforcement:             "active",
+		Include:                 []string{ref},
+		Exclude:                 []string{},
+		BypassActors:            actors,
+		RequirePullRequest:      requirePullRequest,
+		RequiredReviews:         d.RequiredReviews,
+		DismissStaleReviews:     requirePullRequest && d.DismissStaleReviews,
+		RequireCodeOwnerReviews: requirePullRequest && d.RequireCodeOwnerReviews,
+		RequiredStatusChecks:    append([]string{}, d.RequiredStatusChecks...),
+		StrictStatusChecks:      requireStatusChecks && d.StrictStatusChecks,
+		LinearHistory:           d.LinearHistory,
+		BlockForcePushes:        d.BlockForcePushes,
+		BlockDeletions:          d.BlockDeletions,
+		RestrictUpdates:         d.RestrictUpdates,
+	}
+}
+
+// managesBranchProtection tells whether any layer mentions branch protection, rulesets aren't even listed otherwise.
+func (c ConfRoot) managesBranchProtection() bool {
+	layers := []Layer{{Conf: c.Config}}
+	for _, l := range c.Orgs {
+		layers = append(layers, l)
+	}
+	for _, g := range c.Groups {
+		layers = append(layers, g.Layer)
+	}
+	for _, r := range c.Repos {
+		layers = append(layers, r.Layer)
+	}
+	for _, l := range layers {
+		if len(l.BranchProtection) > 0 || len(l.Exclude.BranchProtection) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// syncBranchProtection reconciles the rulesets managed by meta-sync, those no longer configured are deleted.
+func syncBranchProtection(ctx context.Context, client api.Client, repo string, conf Conf) error {
+	logger := slog.With(slog.String("repo", repo))
+	logger.LogAttrs(ctx, slog.LevelInfo, "sync branch protection")
+
+	rulesets, err := client.ListRulesets(ctx, repo)
+	if err != nil {
+		return err
+	}
+	byName := map[string]*api.Ruleset{}
+	for _, r := range rulesets {
+		byName[r.Name] = r
+	}
+
+	declared := map[string]bool{}
+	for _, def := range conf.BranchProtection {
+		want := def.ruleset()
+		declared[want.Name] = true
+		logger := logger.With(slog.String("pattern", def.Pattern))
+		cur := byName[want.Name]
+		if cur == nil {
+			logger.LogAttrs(ctx, slog.LevelInfo, "creating ruleset")
+			if err := client.CreateRuleset(ctx, repo, want.ToAPI()); err != nil {
+				return err
+			}
+			continue
+		}
+		if !reflect.DeepEqual(mutation.FromRuleset(cur), want) {
+			logger.LogAttrs(ctx, slog.LevelInfo, "updating ruleset")
+			if err := client.UpdateRuleset(ctx, repo, cur.GetID(), want.ToAPI()); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, r := range rulesets {
+		if strings.HasPrefix(r.Name, rulesetPrefix) && !declared[r.Name] {
+			logger.LogAttrs(ctx, slog.LevelInfo, "deleting ruleset", slog.String("ruleset", r.Name))
+			if err := client.DeleteRuleset(ctx, repo, r.GetID()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) branchProtection(defs []BranchProtectionDef, scope string) {
+	patterns := map[string]BranchProtectionDef{}
+	for _, def := range defs {
+		if def.Pattern == "" {
+			v.report(def.pos, "branch protection of %s has no pattern", scope)
+			continue
+		}
+		if prev, ok := patterns[def.Pattern]; ok {
+			v.report(def.pos, "branch protection %q of %s is already defined at line %d", def.Pattern, scope, prev.pos.line)
+		} else {
+			patterns[def.Pattern] = def
+		}
+		for _, name := range def.Bypass {
+			if _, ok := bypassActor(name); !ok {
+				v.report(def.pos, "branch protection %q has an invalid bypass %q, expected orgAdmin, admin, maintain, write or app:<id>", def.Pattern, name)
+			}
+		}
+		if def.RestrictUpdates && len(def.Bypass) == 0 {
+			v.report(def.pos, "branch protection %q restricts updates without bypass, nobody could push to the branches", def.Pattern)
+		}
+		if def.RequiredReviews < 0 || def.RequiredReviews > maxRequiredReviews {
+			v.report(def.pos, "branch protection %q requires %d reviews, GitHub allows 0 to %d", def.Pattern, def.RequiredReviews, maxRequiredReviews)
+		}
+	}
+}
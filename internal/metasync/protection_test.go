@@ -0,0 +1,172 @@
+package metasync
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v67/github"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+	"github.com/pmalek/github-pm-groomer/internal/mutation"
+)
+
+const protectionConf = `
+repos: [acme/widgets]
+config:
+  branchProtection:
+    - pattern: main
+      requiredReviews: 1
+      restrictUpdates: true
+      bypass: [admin, app:42, admin]
+`
+
+func ruleset(t *testing.T, client *fake.Client) *api.Ruleset {
+	t.Helper()
+	rulesets, err := client.ListRulesets(context.Background(), "acme/widgets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rulesets) != 1 {
+		t.Fatalf("got %d rulesets, want 1", len(rulesets))
+	}
+	return rulesets[0]
+}
+
+func TestSyncBranchProtection(t *testing.T) {
+	want := &mutation.Ruleset{
+		Name:        "meta-sync: main",
+		Enforcement: "active",
+		Include:     []string{"refs/heads/main"},
+		Exclude:     []string{},
+		BypassActors: []mutation.BypassActor{
+			{Type: "Integration", ID: 42, Mode: "always"},
+			{Type: "RepositoryRole", ID: 5, Mode: "always"},
+		},
+		RequirePullRequest:   true,
+		RequiredReviews:      1,
+		RequiredStatusChecks: []string{},
+		RestrictUpdates:      true,
+	}
+	tests := []struct {
+		name   string
+		change func(r *api.Ruleset)
+	}{
+		{
+			name:   "enforcement",
+			change: func(r *api.Ruleset) { r.Enforcement = "disabled" },
+		},
+		{
+			name:   "excluded refs",
+			change: func(r *api.Ruleset) { r.Conditions.RefName.Exclude = []string{"refs/heads/main"} },
+		},
+		{
+			name:   "bypass actors",
+			change: func(r *api.Ruleset) { r.BypassActors = r.BypassActors[:1] },
+		},
+		{
+			name: "bypass mode",
+			change: func(r *api.Ruleset) {
+				r.BypassActors[0].BypassMode = github.String("pull_request")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFake(t, syncFixture)
+			if err := run(t, client, protectionConf, Opts{}); err != nil {
+				t.Fatal(err)
+			}
+			cur := ruleset(t, client)
+			if got := mutation.FromRuleset(cur); !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+
+			tt.change(cur)
+			if err := client.UpdateRuleset(context.Background(), "acme/widgets", cur.GetID(), cur); err != nil {
+				t.Fatal(err)
+			}
+			if err := run(t, client, protectionConf, Opts{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := mutation.FromRuleset(ruleset(t, client)); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSyncBranchProtectionTwice(t *testing.T) {
+	tests := []struct {
+		name string
+		def  string
+	}{
+		{name: "strict without status checks", def: `{pattern: main, strictStatusChecks: true}`},
+		{name: "reviews options without pull requests", def: `{pattern: main, dismissStaleReviews: true, requireCodeOwnerReviews: true}`},
+		{name: "everything", def: `{pattern: main, requiredReviews: 2, dismissStaleReviews: true, requireCodeOwnerReviews: true, requiredStatusChecks: [ci], strictStatusChecks: true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFake(t, syncFixture)
+			conf := "repos: [acme/widgets]\nconfig:\n  branchProtection:\n    - " + tt.def + "\n"
+			if err := run(t, client, conf, Opts{}); err != nil {
+				t.Fatal(err)
+			}
+			var ops []string
+			recorder := mutation.NewClient(client, func(ctx context.Context, op mutation.Op) error {
+				ops = append(ops, op.String())
+				return nil
+			})
+			opts := Opts{FilePath: writeConf(t, conf), Concurrency: 1}
+			if err := Run(context.Background(), recorder, opts, testNow); err != nil {
+				t.Fatal(err)
+			}
+			if len(ops) > 0 {
+				t.Errorf("the second sync changed %q", ops)
+			}
+		})
+	}
+}
+
+func TestValidateBranchProtection(t *testing.T) {
+	tests := []struct {
+		name string
+		def  string
+		want []string
+	}{
+		{
+			name: "valid",
+			def:  `{pattern: main, restrictUpdates: true, bypass: [orgAdmin, maintain, write, app:1]}`,
+		},
+		{
+			name: "restricted without bypass",
+			def:  `{pattern: main, restrictUpdates: true}`,
+			want: []string{`branch protection "main" restricts updates without bypass, nobody could push to the branches`},
+		},
+		{
+			name: "invalid bypass",
+			def:  `{pattern: main, bypass: [owner, app:x]}`,
+			want: []string{
+				`branch protection "main" has an invalid bypass "owner", expected orgAdmin, admin, maintain, write or app:<id>`,
+				`branch protection "main" has an invalid bypass "app:x", expected orgAdmin, admin, maintain, write or app:<id>`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics, err := Validate(writeConf(t, "config:\n  branchProtection:\n    - "+tt.def+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range diagnostics {
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
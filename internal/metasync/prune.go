@@ -0,0 +1,133 @@
+package metasync
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+type PruneConf struct {
+	// Enabled deletes the labels and milestones which are not in the configuration.
+	Enabled bool `yaml:"enabled"`
+	// Protected are patterns (e.g. `lifecycle/*`) of label names and milestone titles which are never pruned, label
+	// names match regardless of their case.
+	Protected []string `yaml:"protected"`
+	// MaxDeletions aborts the sync when a repository would lose more labels, milestones and access grants, 0 means
+	// no limit.
+	MaxDeletions int `yaml:"maxDeletions"`
+}
+
+func (p PruneConf) protected(name string) bool {
+	for _, pattern := range p.Protected {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// protectedLabel matches case-insensitively like GitHub compares label names.
+func (p PruneConf) protectedLabel(name string) bool {
+	for _, pattern := range p.Protected {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// labelsToPrune returns the existing labels which are neither declared, nor former names of declared labels.
+func labelsToPrune(labels []*api.Label, conf Conf, prune PruneConf) []*api.Label {
+	declared := map[string]bool{}
+	for _, def := range conf.Labels {
+		declared[strings.ToLower(def.Name)] = true
+		for _, previous := range def.Previously {
+			declared[strings.ToLower(previous)] = true
+		}
+	}
+	var res []*api.Label
+	for _, l := range labels {
+		if !declared[strings.ToLower(l.GetName())] && !prune.protectedLabel(l.GetName()) {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// milestonesToPrune returns the existing milestones which are neither declared, nor of a schedule.
+func milestonesToPrune(milestones []*api.Milestone, conf Conf, prune PruneConf) []*api.Milestone {
+	declared := map[string]bool{}
+	for _, def := range conf.Milestones {
+		declared[def.Title] = true
+	}
+	scheduled := func(title string) bool {
+		for _, s := range conf.MilestoneSchedules {
+			if _, ok := s.number(title); ok {
+				return true
+			}
+		}
+		return false
+	}
+	var res []*api.Milestone
+	for _, m := range milestones {
+		if !declared[m.GetTitle()] && !scheduled(m.GetTitle()) && !prune.protected(m.GetTitle()) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// checkDeletions fails when syncing repo would delete more than prune.MaxDeletions labels, milestones and access
+// grants.
+func checkDeletions(ctx context.Context, client api.Client, repo string, conf Conf, prune PruneConf, pruning bool) error {
+	if prune.MaxDeletions <= 0 {
+		return nil
+	}
+	labels, err := client.ListLabels(ctx, repo)
+	if err != nil {
+		return err
+	}
+	milestones, err := client.ListMilestones(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	existingLabels := map[string]bool{}
+	for _, l := range labels {
+		existingLabels[strings.ToLower(l.GetName())] = true
+	}
+	existingMilestones := map[string]bool{}
+	for _, m := range milestones {
+		existingMilestones[m.GetTitle()] = true
+	}
+	deletions := 0
+	for _, def := range conf.Labels {
+		if def.Delete && existingLabels[strings.ToLower(def.Name)] {
+			deletions += 1
+		}
+	}
+	for _, def := range conf.Milestones {
+		if def.Delete && existingMilestones[def.Title] {
+			deletions += 1
+		}
+	}
+	if pruning {
+		deletions += len(labelsToPrune(labels, conf, prune)) + len(milestonesToPrune(milestones, conf, prune))
+	}
+	revocations, err := accessRevocations(ctx, client, repo, conf.Access)
+	if err != nil {
+		return err
+	}
+	deletions += revocations
+	if deletions > prune.MaxDeletions {
+		return fmt.Errorf("%s: refusing to delete %d labels, milestones and access grants, the limit is %d", repo, deletions, prune.MaxDeletions)
+	}
+	return nil
+}
+
+func (p PruneConf) isZero() bool {
+	return !p.Enabled && len(p.Protected) == 0 && p.MaxDeletions == 0
+}
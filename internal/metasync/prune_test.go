@@ -0,0 +1,115 @@
+package metasync
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRunPrune(t *testing.T) {
+	fixture := `
+repos:
+  acme/widgets:
+    labels:
+      - {name: kind/bug, color: d73a4a}
+      - {name: bug, color: ff0000}
+      - {name: lifecycle/stale, color: ededed}
+      - {name: extra, color: 000000}
+    milestones:
+      - {number: 1, title: v1.0, state: open}
+      - {number: 2, title: backlog, state: open}
+`
+	all := []string{"bug ff0000 ", "extra 000000 ", "kind/bug d73a4a ", "lifecycle/stale ededed "}
+	tests := []struct {
+		name           string
+		conf           string
+		prune          bool
+		wantErr        bool
+		wantLabels     []string
+		wantMilestones []string
+	}{
+		{
+			name: "disabled",
+			conf: `
+repos: [acme/widgets]
+config:
+  labels: [{name: kind/bug, color: d73a4a}]
+`,
+			wantLabels:     all,
+			wantMilestones: []string{"backlog open", "v1.0 open"},
+		},
+		{
+			name: "enabled by flag",
+			conf: `
+repos: [acme/widgets]
+config:
+  labels: [{name: kind/bug, color: d73a4a, previously: [bug]}]
+  milestones: [{title: v1.0}]
+`,
+			prune: true,
+			// bug is a former name of kind/bug, it's merged rather than pruned.
+			wantLabels:     []string{"kind/bug d73a4a "},
+			wantMilestones: []string{"v1.0 open"},
+		},
+		{
+			name: "protected",
+			conf: `
+repos: [acme/widgets]
+prune: {enabled: true, protected: ["Lifecycle/*", backlog]}
+config:
+  labels: [{name: kind/bug, color: d73a4a}]
+`,
+			wantLabels:     []string{"kind/bug d73a4a ", "lifecycle/stale ededed "},
+			wantMilestones: []string{"backlog open"},
+		},
+		{
+			name: "disabled for the repo",
+			conf: `
+repos: [{name: acme/widgets, prune: false}]
+prune: {enabled: true}
+config:
+  labels: [{name: kind/bug, color: d73a4a}]
+`,
+			wantLabels:     all,
+			wantMilestones: []string{"backlog open", "v1.0 open"},
+		},
+		{
+			name: "too many deletions",
+			conf: `
+repos: [acme/widgets]
+prune: {enabled: true, maxDeletions: 3}
+config:
+  labels: [{name: kind/bug, color: d73a4a}]
+`,
+			wantErr:        true,
+			wantLabels:     all,
+			wantMilestones: []string{"backlog open", "v1.0 open"},
+		},
+		{
+			name: "explicit deletions count",
+			conf: `
+repos: [acme/widgets]
+prune: {maxDeletions: 1}
+config:
+  labels: [{name: bug, delete: true}, {name: extra, delete: true}]
+`,
+			wantErr:        true,
+			wantLabels:     all,
+			wantMilestones: []string{"backlog open", "v1.0 open"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFake(t, fixture)
+			err := run(t, client, tt.conf, Opts{Prune: tt.prune})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one: %v", err, tt.wantErr)
+			}
+			if got := labels(t, client, "acme/widgets"); !slices.Equal(got, tt.wantLabels) {
+				t.Errorf("labels are %q, want %q", got, tt.wantLabels)
+			}
+			if got := milestones(t, client, "acme/widgets"); !slices.Equal(got, tt.wantMilestones) {
+				t.Errorf("milestones are %q, want %q", got, tt.wantMilestones)
+			}
+		})
+	}
+}
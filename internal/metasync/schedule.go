@@ -0,0 +1,212 @@
+package metasync
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MilestoneScheduleDef declares milestones due at a regular interval, such as the releases of a release train.
+// It's expanded into the milestones of the layer declaring it when syncing: the next Open ones are created and kept
+// open, the past ones are closed when they exist. A milestone of the layer with the same title takes precedence.
+type MilestoneScheduleDef struct {
+	// Title of each milestone, `{n}` is replaced by its number, e.g. `v1.{n}`.
+	Title string `yaml:"title"`
+	// Description of each milestone, `{n}`, `{title}` and `{due}` are replaced.
+	Description string `yaml:"description,omitempty"`
+	// Start is the due date of the first milestone.
+	Start Time `yaml:"start"`
+	// First is the number of the first milestone.
+	First int `yaml:"first,omitempty"`
+	// Every is the interval between due dates in days or weeks, e.g. `14d` or `6w`.
+	Every string `yaml:"every"`
+	// Open is the number of upcoming milestones kept open.
+	Open int `yaml:"open"`
+
+	pos position
+}
+
+func (s *MilestoneScheduleDef) UnmarshalYAML(value *yaml.Node) error {
+	type plain MilestoneScheduleDef
+	if err := value.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.pos = positionOf(value)
+	return nil
+}
+
+// interval parses Every.
+func (s MilestoneScheduleDef) interval() (time.Duration, error) {
+	unit := 24 * time.Hour
+	n, ok := strings.CutSuffix(s.Every, "d")
+	if !ok {
+		n, ok = strings.CutSuffix(s.Every, "w")
+		unit *= 7
+	}
+	count, err := strconv.Atoi(n)
+	if !ok || err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid interval %q, expected a number of days or weeks such as 14d or 6w", s.Every)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// instance is the i-th milestone of the schedule.
+func (s MilestoneScheduleDef) instance(i int, every time.Duration) MilestoneDef {
+	n := strconv.Itoa(s.First + i)
+	due := s.Start.Add(time.Duration(i) * every)
+	title := strings.ReplaceAll(s.Title, "{n}", n)
+	return MilestoneDef{
+		Title: title,
+		// Due dates are the end of the day, see Time.
+		DueDate: Time{Time: due},
+		Description: strings.NewReplacer(
+			"{n}", n, "{title}", title, "{due}", due.Add(-24*time.Hour).Format(time.DateOnly),
+		).Replace(s.Description),
+		pos: s.pos,
+	}
+}
+
+// upcoming are the next Open milestones of the schedule as of now.
+func (s MilestoneScheduleDef) upcoming(now time.Time) []MilestoneDef {
+	every, err := s.interval()
+	if err != nil || s.Start.IsZero() {
+		return nil
+	}
+	first := 0
+	if !s.Start.After(now) {
+		first = int(now.Sub(s.Start.Time)/every) + 1
+	}
+	var res []MilestoneDef
+	for i := first; i < first+s.Open; i++ {
+		res = append(res, s.instance(i, every))
+	}
+	return res
+}
+
+// number returns the number of the milestone of the schedule titled title.
+func (s MilestoneScheduleDef) number(title string) (int, bool) {
+	parts := strings.Split(s.Title, "{n}")
+	if len(parts) < 2 {
+		return 0, false
+	}
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	match := regexp.MustCompile("^" + strings.Join(parts, `(\d+)`) + "$").FindStringSubmatch(title)
+	if match == nil {
+		return 0, false
+	}
+	for _, n := range match[2:] {
+		if n != match[1] {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || strconv.Itoa(n) != match[1] || n < s.First {
+		return 0, false
+	}
+	return n, true
+}
+
+// past returns the milestone of the schedule titled title, closed, when it was due by now. Past milestones are
+// matched by title rather than generated so there are no more of them than milestones in the repository.
+func (s MilestoneScheduleDef) past(title string, now time.Time) (MilestoneDef, bool) {
+	every, err := s.interval()
+	if err != nil || s.Start.IsZero() {
+		return MilestoneDef{}, false
+	}
+	n, ok := s.number(title)
+	if !ok {
+		return MilestoneDef{}, false
+	}
+	def := s.instance(n-s.First, every)
+	if def.DueDate.After(now) {
+		return MilestoneDef{}, false
+	}
+	def.Closed = true
+	return def, true
+}
+
+// withSchedules returns conf with the upcoming milestones of its schedules.
+func (c Conf) withSchedules(now time.Time) Conf {
+	titles := map[string]bool{}
+	for _, def := range c.Milestones {
+		titles[def.Title] = true
+	}
+	c.Milestones = append([]MilestoneDef{}, c.Milestones...)
+	for _, s := range c.MilestoneSchedules {
+		for _, def := range s.upcoming(now) {
+			if !titles[def.Title] {
+				c.Milestones = append(c.Milestones, def)
+			}
+		}
+	}
+	return c
+}
+
+// pastMilestone returns the past milestone of a schedule of conf titled title, nil if there's none. Declared
+// milestones take precedence over the ones of the schedules.
+func (c Conf) pastMilestone(title string, now time.Time) *MilestoneDef {
+	for _, def := range c.Milestones {
+		if def.Title == title {
+			return nil
+		}
+	}
+	for _, s := range c.MilestoneSchedules {
+		if def, ok := s.past(title, now); ok {
+			return &def
+		}
+	}
+	return nil
+}
+
+// expandSchedules adds the milestones of the schedules of every layer to the layer.
+func (c ConfRoot) expandSchedules(now time.Time) ConfRoot {
+	c.Config = c.Config.withSchedules(now)
+	c.Orgs = maps.Clone(c.Orgs)
+	for org, layer := range c.Orgs {
+		layer.Conf = layer.withSchedules(now)
+		c.Orgs[org] = layer
+	}
+	c.Groups = maps.Clone(c.Groups)
+	for name, group := range c.Groups {
+		group.Conf = group.withSchedules(now)
+		c.Groups[name] = group
+	}
+	c.Repos = append([]RepoDef{}, c.Repos...)
+	for i := range c.Repos {
+		c.Repos[i].Conf = c.Repos[i].withSchedules(now)
+	}
+	return c
+}
+
+func (v *validator) milestoneSchedules(defs []MilestoneScheduleDef, scope string) {
+	titles := map[string]MilestoneScheduleDef{}
+	for _, def := range defs {
+		if !strings.Contains(def.Title, "{n}") {
+			v.report(def.pos, "milestone schedule %q of %s needs {n} in its title", def.Title, scope)
+		}
+		if prev, ok := titles[def.Title]; ok {
+			v.report(def.pos, "milestone schedule %q of %s is already defined at line %d", def.Title, scope, prev.pos.line)
+		} else {
+			titles[def.Title] = def
+		}
+		switch {
+		case def.Start.invalid != "":
+			v.report(def.pos, "milestone schedule %q has an invalid start %q, expected YYYY-MM-DD", def.Title, def.Start.invalid)
+		case def.Start.IsZero():
+			v.report(def.pos, "milestone schedule %q has no start", def.Title)
+		}
+		if _, err := def.interval(); err != nil {
+			v.report(def.pos, "milestone schedule %q has an %s", def.Title, err)
+		}
+		if def.Open < 0 {
+			v.report(def.pos, "milestone schedule %q keeps %d milestones open, expected 0 or more", def.Title, def.Open)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package metasync
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func schedule(t *testing.T, def string) MilestoneScheduleDef {
+	t.Helper()
+	var s MilestoneScheduleDef
+	if err := yaml.Unmarshal([]byte(def), &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// titles lists the milestones as `title due`, due being the configured date.
+func titles(defs []MilestoneDef) []string {
+	var res []string
+	for _, def := range defs {
+		res = append(res, def.Title+" "+def.DueDate.Add(-24*time.Hour).Format(time.DateOnly))
+	}
+	return res
+}
+
+func TestScheduleUpcoming(t *testing.T) {
+	s := schedule(t, `{title: "v1.{n}", start: 2026-01-05, every: 2w, open: 2}`)
+	tests := []struct {
+		name string
+		now  time.Time
+		want []string
+	}{
+		{
+			name: "before the start",
+			now:  time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{"v1.0 2026-01-05", "v1.1 2026-01-19"},
+		},
+		{
+			name: "on the due day",
+			now:  time.Date(2026, 1, 19, 12, 0, 0, 0, time.UTC),
+			want: []string{"v1.1 2026-01-19", "v1.2 2026-02-02"},
+		},
+		{
+			name: "once the due day is over",
+			now:  time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC),
+			want: []string{"v1.2 2026-02-02", "v1.3 2026-02-16"},
+		},
+		{
+			name: "years later",
+			now:  time.Date(2036, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{"v1.261 2036-01-07", "v1.262 2036-01-21"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titles(s.upcoming(tt.now)); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulePast(t *testing.T) {
+	s := schedule(t, `{title: "v{n}.{n}", start: 2026-01-05, first: 3, every: 7d, open: 1}`)
+	now := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "v3.3", want: "v3.3 2026-01-05"},
+		{title: "v5.5", want: "v5.5 2026-01-19"},
+		// Not due yet.
+		{title: "v6.6"},
+		// Before the first one.
+		{title: "v2.2"},
+		{title: "v3.4"},
+		{title: "v03.03"},
+		{title: "v3.3-rc"},
+		{title: "backlog"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			def, ok := s.past(tt.title, now)
+			if got := titles([]MilestoneDef{def}); ok != (tt.want != "") || ok && got[0] != tt.want {
+				t.Errorf("got %q %v, want %q", got, ok, tt.want)
+			}
+			if ok && !def.Closed {
+				t.Error("past milestone isn't closed")
+			}
+		})
+	}
+}
+
+func TestRunSchedules(t *testing.T) {
+	fixture := `
+repos:
+  acme/widgets:
+    milestones:
+      - {number: 1, title: day-2, state: open}
+      - {number: 2, title: day-9000, state: open}
+      - {number: 3, title: day-9555, state: closed}
+      - {number: 4, title: backlog, state: open}
+`
+	// A daily schedule running for decades only closes the past milestones which exist.
+	conf := `
+repos: [acme/widgets]
+prune: {enabled: true}
+config:
+  milestones: [{title: day-9000}]
+  milestoneSchedules:
+    - {title: "day-{n}", start: 2000-01-01, every: 1d, open: 2}
+`
+	client := newFake(t, fixture)
+	if err := run(t, client, conf, Opts{}); err != nil {
+		t.Fatal(err)
+	}
+	// backlog is pruned, the milestones of the schedule aren't.
+	want := []string{
+		"day-2 closed",
+		// Declared milestones take precedence.
+		"day-9000 open",
+		"day-9555 closed",
+		"day-9556 open",
+		"day-9557 open",
+	}
+	if got := milestones(t, client, "acme/widgets"); !slices.Equal(got, want) {
+		t.Errorf("milestones are %q, want %q", got, want)
+	}
+}
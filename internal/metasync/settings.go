@@ -0,0 +1,163 @@
+package metasync
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v67/github"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+// RepoSettings are the repository settings to sync, unset ones are left as they are. Each layer overrides the
+// settings it sets.
+type RepoSettings struct {
+	Description *string `yaml:"description,omitempty"`
+	Homepage    *string `yaml:"homepage,omitempty"`
+	// Topics replace all the topics of the repository.
+	Topics              []string `yaml:"topics,omitempty"`
+	HasIssues           *bool    `yaml:"hasIssues,omitempty"`
+	HasWiki             *bool    `yaml:"hasWiki,omitempty"`
+	HasProjects         *bool    `yaml:"hasProjects,omitempty"`
+	HasDiscussions      *bool    `yaml:"hasDiscussions,omitempty"`
+	AllowMergeCommit    *bool    `yaml:"allowMergeCommit,omitempty"`
+	AllowSquashMerge    *bool    `yaml:"allowSquashMerge,omitempty"`
+	AllowRebaseMerge    *bool    `yaml:"allowRebaseMerge,omitempty"`
+	DeleteBranchOnMerge *bool    `yaml:"deleteBranchOnMerge,omitempty"`
+	DefaultBranch       *string  `yaml:"defaultBranch,omitempty"`
+
+	pos position
+}
+
+func (s *RepoSettings) UnmarshalYAML(value *yaml.Node) error {
+	type plain RepoSettings
+	if err := value.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.pos = positionOf(value)
+	return nil
+}
+
+// merge returns s with the settings set by over replacing its own.
+func (s *RepoSettings) merge(over *RepoSettings) *RepoSettings {
+	if over == nil {
+		return s
+	}
+	if s == nil {
+		return over
+	}
+	res := *s
+	pick := func(into **bool, from *bool) {
+		if from != nil {
+			*into = from
+		}
+	}
+	pickString := func(into **string, from *string) {
+		if from != nil {
+			*into = from
+		}
+	}
+	pickString(&res.Description, over.Description)
+	pickString(&res.Homepage, over.Homepage)
+	if over.Topics != nil {
+		res.Topics = over.Topics
+	}
+	pick(&res.HasIssues, over.HasIssues)
+	pick(&res.HasWiki, over.HasWiki)
+	pick(&res.HasProjects, over.HasProjects)
+	pick(&res.HasDiscussions, over.HasDiscussions)
+	pick(&res.AllowMergeCommit, over.AllowMergeCommit)
+	pick(&res.AllowSquashMerge, over.AllowSquashMerge)
+	pick(&res.AllowRebaseMerge, over.AllowRebaseMerge)
+	pick(&res.DeleteBranchOnMerge, over.DeleteBranchOnMerge)
+	pickString(&res.DefaultBranch, over.DefaultBranch)
+	return &res
+}
+
+// changes returns the settings of s which differ from the repository, nil when there are none.
+func (s *RepoSettings) changes(cur *api.Repository) *api.Repository {
+	r := (*github.Repository)(cur)
+	res := &api.Repository{}
+	changed := false
+	diffString := func(into **string, want *string, have string) {
+		if want != nil && *want != have {
+			*into = github.String(*want)
+			changed = true
+		}
+	}
+	diffBool := func(into **bool, want *bool, have bool) {
+		if want != nil && *want != have {
+			*into = github.Bool(*want)
+			changed = true
+		}
+	}
+	diffString(&res.Description, s.Description, r.GetDescription())
+	diffString(&res.Homepage, s.Homepage, r.GetHomepage())
+	if s.Topics != nil {
+		want, have := slices.Clone(s.Topics), slices.Clone(r.Topics)
+		sort.Strings(want)
+		sort.Strings(have)
+		if !slices.Equal(want, have) {
+			res.Topics = slices.Clone(s.Topics)
+			changed = true
+		}
+	}
+	diffBool(&res.HasIssues, s.HasIssues, r.GetHasIssues())
+	diffBool(&res.HasWiki, s.HasWiki, r.GetHasWiki())
+	diffBool(&res.HasProjects, s.HasProjects, r.GetHasProjects())
+	diffBool(&res.HasDiscussions, s.HasDiscussions, r.GetHasDiscussions())
+	diffBool(&res.AllowMergeCommit, s.AllowMergeCommit, r.GetAllowMergeCommit())
+	diffBool(&res.AllowSquashMerge, s.AllowSquashMerge, r.GetAllowSquashMerge())
+	diffBool(&res.AllowRebaseMerge, s.AllowRebaseMerge, r.GetAllowRebaseMerge())
+	diffBool(&res.DeleteBranchOnMerge, s.DeleteBranchOnMerge, r.GetDeleteBranchOnMerge())
+	diffString(&res.DefaultBranch, s.DefaultBranch, r.GetDefaultBranch())
+	if !changed {
+		return nil
+	}
+	return res
+}
+
+func syncSettings(ctx context.Context, client api.Client, repo string, conf Conf) error {
+	if conf.Settings == nil {
+		return nil
+	}
+	logger := slog.With(slog.String("repo", repo))
+	logger.LogAttrs(ctx, slog.LevelInfo, "sync settings")
+
+	cur, err := client.GetRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+	update := conf.Settings.changes(cur)
+	if update == nil {
+		return nil
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "updating settings")
+	return client.UpdateRepository(ctx, repo, update)
+}
+
+// topicRe is what GitHub accepts as a topic.
+var topicRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+func (v *validator) settings(s *RepoSettings) {
+	if s == nil {
+		return
+	}
+	for _, topic := range s.Topics {
+		if !topicRe.MatchString(topic) {
+			v.report(s.pos, "topic %q is invalid, expected up to 50 lowercase letters, numbers and hyphens", topic)
+		}
+	}
+	if s.DefaultBranch != nil && strings.TrimSpace(*s.DefaultBranch) == "" {
+		v.report(s.pos, "defaultBranch can't be empty")
+	}
+	if s.AllowMergeCommit != nil && s.AllowSquashMerge != nil && s.AllowRebaseMerge != nil &&
+		!*s.AllowMergeCommit && !*s.AllowSquashMerge && !*s.AllowRebaseMerge {
+		v.report(s.pos, "at least one merge method has to be allowed")
+	}
+}
@@ -3,9 +3,7 @@ package metasync
 import (
 	"context"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -15,62 +13,21 @@ import (
 
 	"github.com/google/go-github/v67/github"
 	"github.com/pmalek/github-pm-groomer/internal/github/api"
-	"github.com/pmalek/github-pm-groomer/internal/utils"
-	"gopkg.in/yaml.v3"
+	"github.com/pmalek/github-pm-groomer/internal/issues"
 )
 
 type Opts struct {
 	FilePath    string
 	Concurrency int
-}
-
-type ConfRoot struct {
-	Repos  []string `yaml:"repos"`
-	Config Conf     `yaml:"config"`
-}
-
-type Conf struct {
-	Labels     []LabelDef     `yaml:"labels"`
-	Milestones []MilestoneDef `yaml:"milestones"`
-}
-
-type Time struct {
-	time.Time
-}
-
-func (t *Time) UnmarshalYAML(value *yaml.Node) error {
-	var s string
-	if err := value.Decode(&s); err != nil {
-		return err
-	}
-	parsed, err := time.Parse("2006-01-02", s)
-	if err != nil {
-		return err
-	}
-	*t = Time{
-		Time: parsed.Add(time.Hour * 24),
-	}
-	return nil
-}
-
-type MilestoneDef struct {
-	Title       string `yaml:"title"`
-	Closed      bool   `yaml:"closed"`
-	DueDate     Time   `yaml:"dueDate"`
-	Description string `yaml:"description"`
-	Delete      bool   `yaml:"delete"`
-}
-
-type LabelDef struct {
-	Color       string `yaml:"color"`
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Delete      bool   `yaml:"delete"`
+	// Prune enables pruning for all the repositories which don't disable it explicitly.
+	Prune bool
 }
 
 func (o Opts) Validate() error {
-	if _, err := os.Stat(o.FilePath); err != nil {
-		return err
+	if !isURL(o.FilePath) {
+		if _, err := os.Stat(o.FilePath); err != nil {
+			return err
+		}
 	}
 	if o.Concurrency <= 0 {
 		return fmt.Errorf("concurrency must be greater than 0, got %d", o.Concurrency)
@@ -85,21 +42,68 @@ func Run(ctx context.Context, client api.Client, opts Opts, now time.Time) error
 		return err
 	}
 
-	// Check if each repo is valid.
-	for _, repo := range conf.Repos {
-		if _, _, err := utils.OrgRepo(repo); err != nil {
+	if diagnostics := conf.validate(); len(diagnostics) > 0 {
+		return invalidConfError(diagnostics)
+	}
+	conf = conf.expandSchedules(now)
+
+	repos, err := conf.resolveRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	pruning := func(repo RepoDef) bool {
+		if repo.Prune != nil {
+			return *repo.Prune
+		}
+		return conf.Prune.Enabled || opts.Prune
+	}
+	confs := map[string]Conf{}
+	for _, repo := range repos {
+		confs[repo.Name] = conf.Resolve(repo)
+	}
+	// Check all the repositories first so nothing changes if any of them would lose too much.
+	for _, repo := range repos {
+		if err := checkDeletions(ctx, client, repo.Name, confs[repo.Name], conf.Prune, pruning(repo)); err != nil {
+			return err
+		}
+	}
+
+	for _, repo := range repos {
+		if err := syncSettings(ctx, client, repo.Name, confs[repo.Name]); err != nil {
+			return err
+		}
+	}
+
+	for _, repo := range repos {
+		if err := syncAccess(ctx, client, repo.Name, confs[repo.Name]); err != nil {
+			return err
+		}
+	}
+
+	if conf.managesBranchProtection() {
+		for _, repo := range repos {
+			if err := syncBranchProtection(ctx, client, repo.Name, confs[repo.Name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, repo := range repos {
+		if err := syncLabels(ctx, client, repo.Name, confs[repo.Name], conf.Prune, pruning(repo), opts.Concurrency); err != nil {
 			return err
 		}
 	}
 
-	for _, repo := range conf.Repos {
-		if err := syncLabels(ctx, client, repo, conf, opts.Concurrency); err != nil {
+	for _, repo := range repos {
+		if err := syncMilestones(ctx, client, repo.Name, confs[repo.Name], conf.Prune, pruning(repo), opts.Concurrency, now); err != nil {
 			return err
 		}
 	}
 
-	for _, repo := range conf.Repos {
-		if err := syncMilestones(ctx, client, repo, conf, opts.Concurrency); err != nil {
+	sources := fileSources{}
+	for _, repo := range repos {
+		if err := syncFiles(ctx, client, repo.Name, confs[repo.Name], sources); err != nil {
 			return err
 		}
 	}
@@ -111,7 +115,9 @@ func syncLabels(
 	ctx context.Context,
 	client api.Client,
 	repo string,
-	labelConf ConfRoot,
+	conf Conf,
+	prune PruneConf,
+	pruning bool,
 	concurrency int,
 ) error {
 	logger := slog.With(slog.String("repo", repo))
@@ -121,16 +127,31 @@ func syncLabels(
 	if err != nil {
 		return err
 	}
-	byName := map[string]*api.Label{}
-	for _, l := range labels {
-		byName[*l.Name] = l
-	}
+	byName := labelsByName(labels)
 	errGroup, ctx := errgroup.WithContext(ctx)
-	for _, def := range labelConf.Config.Labels {
+	if pruning {
+		for _, l := range labelsToPrune(labels, conf, prune) {
+			errGroup.Go(func() error {
+				logger.LogAttrs(ctx, slog.LevelInfo, "pruning label", slog.String("label", *l.Name))
+				return client.DeleteLabel(ctx, repo, *l.Name)
+			})
+		}
+	}
+	for _, def := range conf.Labels {
 		errGroup.Go(func() error {
+			byName := byName
+			attempts := 0
 			return retry.Do(func() error {
 				logger := logger.With(slog.String("label", def.Name))
-				cur := byName[def.Name]
+				// A failed attempt may have renamed or merged labels already, start again from what's there now.
+				if attempts++; attempts > 1 {
+					labels, err := client.ListLabels(ctx, repo)
+					if err != nil {
+						return err
+					}
+					byName = labelsByName(labels)
+				}
+				cur := byName[strings.ToLower(def.Name)]
 
 				if def.Delete {
 					if cur != nil {
@@ -143,6 +164,29 @@ func syncLabels(
 				}
 
 				label := &api.Label{Color: &def.Color, Name: &def.Name, Description: &def.Description}
+				for _, previous := range def.Previously {
+					old := byName[strings.ToLower(previous)]
+					if old == nil || strings.EqualFold(previous, def.Name) {
+						continue
+					}
+					if cur == nil {
+						// Renaming keeps the label on all its issues and pull requests.
+						logger.LogAttrs(ctx, slog.LevelInfo, "renaming label", slog.String("previous", *old.Name))
+						if err := client.UpdateLabel(ctx, repo, *old.Name, label); err != nil {
+							return err
+						}
+						cur = label
+						continue
+					}
+					logger.LogAttrs(ctx, slog.LevelInfo, "merging label", slog.String("previous", *old.Name))
+					if err := migrateLabel(ctx, client, repo, *old.Name, def.Name); err != nil {
+						return err
+					}
+					if err := client.DeleteLabel(ctx, repo, *old.Name); err != nil {
+						return err
+					}
+				}
+
 				if cur == nil {
 					logger.LogAttrs(ctx, slog.LevelInfo, "creating label")
 					if err := client.CreateLabel(ctx, repo, label); err != nil {
@@ -151,7 +195,7 @@ func syncLabels(
 					return nil
 				}
 
-				if cur.Color != label.Color || cur.Description != label.Description {
+				if cur.GetName() != def.Name || !strings.EqualFold(cur.GetColor(), def.Color) || cur.GetDescription() != def.Description {
 					logger.LogAttrs(ctx, slog.LevelInfo, "updating label")
 					if err := client.UpdateLabel(ctx, repo, *cur.Name, label); err != nil {
 						return err
@@ -177,12 +221,52 @@ func syncLabels(
 	return nil
 }
 
+// labelsByName indexes labels by their lowercased name as label names are case-insensitive on GitHub.
+func labelsByName(labels []*api.Label) map[string]*api.Label {
+	res := make(map[string]*api.Label, len(labels))
+	for _, l := range labels {
+		res[strings.ToLower(*l.Name)] = l
+	}
+	return res
+}
+
+// migrateLabel moves all the issues and pull requests with the label from to the label to.
+func migrateLabel(ctx context.Context, client api.Client, repo string, from string, to string) error {
+	// Relabeling changes what the listing returns so collect everything before changing anything. Pull requests
+	// are included as they lose the label when it's deleted.
+	var toMigrate []*api.Issue
+	iterator := issues.Selector{Repo: repo, Labels: from, State: "all", IncludePullRequests: true}.Iterator(ctx, client, time.Now())
+	for {
+		issue, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if issue == nil {
+			break
+		}
+		toMigrate = append(toMigrate, issue)
+	}
+	for _, issue := range toMigrate {
+		newLabels := issue.RemoveLabel(from)
+		if !issue.HasLabel(to) {
+			newLabels = append(newLabels, to)
+		}
+		if err := client.UpdateLabels(ctx, repo, *issue.Number, newLabels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func syncMilestones(
 	ctx context.Context,
 	client api.Client,
 	repo string,
-	labelConf ConfRoot,
+	conf Conf,
+	prune PruneConf,
+	pruning bool,
 	concurrency int,
+	now time.Time,
 ) error {
 	logger := slog.With(slog.String("repo", repo))
 	logger.LogAttrs(ctx, slog.LevelInfo, "sync milestones")
@@ -192,11 +276,23 @@ func syncMilestones(
 		return err
 	}
 	byTitle := map[string]*api.Milestone{}
+	defs := append([]MilestoneDef{}, conf.Milestones...)
 	for _, l := range milestones {
 		byTitle[*l.Title] = l
+		if def := conf.pastMilestone(*l.Title, now); def != nil {
+			defs = append(defs, *def)
+		}
 	}
 	errGroup, ctx := errgroup.WithContext(ctx)
-	for _, def := range labelConf.Config.Milestones {
+	if pruning {
+		for _, m := range milestonesToPrune(milestones, conf, prune) {
+			errGroup.Go(func() error {
+				logger.LogAttrs(ctx, slog.LevelInfo, "pruning milestone", slog.String("milestone", *m.Title))
+				return client.DeleteMilestone(ctx, repo, *m.Number)
+			})
+		}
+	}
+	for _, def := range defs {
 		errGroup.Go(func() error {
 			retry.Do(func() error {
 				logger := logger.With(slog.String("milestone", def.Title))
@@ -231,7 +327,7 @@ func syncMilestones(
 					return nil
 				}
 
-				if cur.State != milestone.State || cur.Description != milestone.Description {
+				if cur.GetState() != c || cur.GetDescription() != def.Description || !sameDay(cur.DueOn, milestone.DueOn) {
 					logger.LogAttrs(ctx, slog.LevelInfo, "updating milestone")
 					if err := client.UpdateMilestone(ctx, repo, *cur.Number, milestone); err != nil {
 						return err
@@ -255,6 +351,14 @@ func syncMilestones(
 	return nil
 }
 
+// sameDay compares due dates, GitHub stores them as timestamps but only the day matters.
+func sameDay(a *github.Timestamp, b *github.Timestamp) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.UTC().Format(time.DateOnly) == b.UTC().Format(time.DateOnly)
+}
+
 func onRetryErrorHandler(ctx context.Context) func(_ uint, err error) {
 	return func(n uint, err error) {
 		if errRL, ok := err.(*github.RateLimitError); ok {
@@ -294,31 +398,3 @@ func onRetryErrorHandler(ctx context.Context) func(_ uint, err error) {
 		slog.Log(ctx, slog.LevelWarn, "err on request", slog.String("err", err.Error()))
 	}
 }
-
-func parseConf(path string) (ConfRoot, error) {
-	out := ConfRoot{}
-	var b []byte
-	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
-		r, err := http.Get(path)
-		if err != nil {
-			return out, err
-		}
-		if r.StatusCode != 200 {
-			return out, fmt.Errorf("invalid status code: %d", r.StatusCode)
-		}
-		b, err = io.ReadAll(r.Body)
-		if err != nil {
-			return out, err
-		}
-	} else {
-		var err error
-		b, err = os.ReadFile(path)
-		if err != nil {
-			return out, err
-		}
-	}
-	if err := yaml.Unmarshal(b, &out); err != nil {
-		return out, err
-	}
-	return out, nil
-}
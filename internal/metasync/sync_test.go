@@ -0,0 +1,307 @@
+package metasync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// writeFiles writes files relative to a temporary directory and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeConf(t *testing.T, conf string) string {
+	t.Helper()
+	return filepath.Join(writeFiles(t, map[string]string{"meta-sync.yaml": conf}), "meta-sync.yaml")
+}
+
+func newFake(t *testing.T, fixture string) *fake.Client {
+	t.Helper()
+	var f fake.Fixture
+	if err := yaml.Unmarshal([]byte(fixture), &f); err != nil {
+		t.Fatal(err)
+	}
+	client := fake.New(f)
+	client.Now = func() time.Time { return testNow }
+	return client
+}
+
+func run(t *testing.T, client *fake.Client, conf string, opts Opts) error {
+	t.Helper()
+	opts.FilePath = writeConf(t, conf)
+	if opts.Concurrency == 0 {
+		opts.Concurrency = 2
+	}
+	return Run(context.Background(), client, opts, testNow)
+}
+
+// labels lists the labels of repo as `name color description`, sorted.
+func labels(t *testing.T, client *fake.Client, repo string) []string {
+	t.Helper()
+	labels, err := client.ListLabels(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, l := range labels {
+		res = append(res, l.GetName()+" "+l.GetColor()+" "+l.GetDescription())
+	}
+	slices.Sort(res)
+	return res
+}
+
+// milestones lists the milestones of repo as `title state`, sorted.
+func milestones(t *testing.T, client *fake.Client, repo string) []string {
+	t.Helper()
+	milestones, err := client.ListMilestones(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, m := range milestones {
+		res = append(res, m.GetTitle()+" "+m.GetState())
+	}
+	slices.Sort(res)
+	return res
+}
+
+const syncFixture = `
+repos:
+  acme/widgets:
+    labels:
+      - {name: kind/bug, color: ff0000, description: Old}
+      - {name: wontfix, color: ffffff}
+      - {name: extra, color: 000000}
+    milestones:
+      - {number: 1, title: v1.0, state: open}
+`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		conf           string
+		wantLabels     []string
+		wantMilestones []string
+	}{
+		{
+			name: "nothing declared",
+			conf: `repos: [acme/widgets]`,
+			wantLabels: []string{
+				"extra 000000 ",
+				"kind/bug ff0000 Old",
+				"wontfix ffffff ",
+			},
+			wantMilestones: []string{"v1.0 open"},
+		},
+		{
+			name: "labels created, updated and deleted",
+			conf: `
+repos: [acme/widgets]
+config:
+  labels:
+    - {name: kind/bug, color: d73a4a, description: Something is broken}
+    - {name: kind/feature, color: a2eeef}
+    - {name: wontfix, delete: true}
+`,
+			wantLabels: []string{
+				"extra 000000 ",
+				"kind/bug d73a4a Something is broken",
+				"kind/feature a2eeef ",
+			},
+			wantMilestones: []string{"v1.0 open"},
+		},
+		{
+			name: "milestones created and closed",
+			conf: `
+repos: [acme/widgets]
+config:
+  milestones:
+    - {title: v1.0, closed: true}
+    - {title: v1.1, dueDate: 2026-04-01}
+`,
+			wantLabels: []string{
+				"extra 000000 ",
+				"kind/bug ff0000 Old",
+				"wontfix ffffff ",
+			},
+			wantMilestones: []string{"v1.0 closed", "v1.1 open"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFake(t, syncFixture)
+			if err := run(t, client, tt.conf, Opts{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := labels(t, client, "acme/widgets"); !slices.Equal(got, tt.wantLabels) {
+				t.Errorf("labels are %q, want %q", got, tt.wantLabels)
+			}
+			if got := milestones(t, client, "acme/widgets"); !slices.Equal(got, tt.wantMilestones) {
+				t.Errorf("milestones are %q, want %q", got, tt.wantMilestones)
+			}
+		})
+	}
+}
+
+func TestRunInvalidConf(t *testing.T) {
+	client := newFake(t, syncFixture)
+	err := run(t, client, "repos: [acme/widgets]\nconfig:\n  labels:\n    - {name: bug, color: red}\n", Opts{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := labels(t, client, "acme/widgets"); len(got) != 3 {
+		t.Errorf("labels changed to %q", got)
+	}
+}
+
+// issueLabels lists the labels of every issue and pull request of repo as `number:label`, sorted.
+func issueLabels(t *testing.T, client *fake.Client, repo string) []string {
+	t.Helper()
+	issues, err := client.GetIssues(context.Background(), repo, api.IssueListOptions{State: "all"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, i := range issues {
+		for _, l := range i.Labels {
+			res = append(res, strconv.Itoa(*i.Number)+":"+l.GetName())
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+func TestRunRename(t *testing.T) {
+	conf := `
+repos: [acme/widgets]
+config:
+  labels:
+    - {name: kind/bug, color: d73a4a, previously: [bug]}
+`
+	tests := []struct {
+		name       string
+		fixture    string
+		wantLabels []string
+		wantIssues []string
+	}{
+		{
+			name: "renamed in place",
+			fixture: `
+repos:
+  acme/widgets:
+    labels:
+      - {name: bug, color: ff0000}
+    issues:
+      - {number: 1, title: Issue, labels: [bug]}
+      - {number: 2, title: Pull request, labels: [bug], pullRequest: true}
+`,
+			wantLabels: []string{"kind/bug d73a4a "},
+			wantIssues: []string{"1:kind/bug", "2:kind/bug"},
+		},
+		{
+			name: "merged into the existing label",
+			fixture: `
+repos:
+  acme/widgets:
+    labels:
+      - {name: bug, color: ff0000}
+      - {name: kind/bug, color: d73a4a}
+      - {name: triage, color: ededed}
+    issues:
+      - {number: 1, title: Issue, labels: [bug, triage]}
+      - {number: 2, title: Pull request, labels: [bug], pullRequest: true}
+      - {number: 3, title: Both, labels: [bug, kind/bug], state: closed}
+`,
+			wantLabels: []string{"kind/bug d73a4a ", "triage ededed "},
+			wantIssues: []string{"1:kind/bug", "1:triage", "2:kind/bug", "3:kind/bug"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFake(t, tt.fixture)
+			if err := run(t, client, conf, Opts{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := labels(t, client, "acme/widgets"); !slices.Equal(got, tt.wantLabels) {
+				t.Errorf("labels are %q, want %q", got, tt.wantLabels)
+			}
+			if got := issueLabels(t, client, "acme/widgets"); !slices.Equal(got, tt.wantIssues) {
+				t.Errorf("issue labels are %q, want %q", got, tt.wantIssues)
+			}
+		})
+	}
+}
+
+// flakyClient reports the first label deletion as failed even though it went through, like a dropped response.
+type flakyClient struct {
+	*fake.Client
+	failed bool
+}
+
+func (c *flakyClient) DeleteLabel(ctx context.Context, orgRepo string, name string) error {
+	if err := c.Client.DeleteLabel(ctx, orgRepo, name); err != nil {
+		return err
+	}
+	if !c.failed {
+		c.failed = true
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func TestRunRenameRetried(t *testing.T) {
+	client := newFake(t, `
+repos:
+  acme/widgets:
+    labels:
+      - {name: bug, color: ff0000}
+      - {name: defect, color: ff0000}
+    issues:
+      - {number: 1, title: Issue, labels: [bug]}
+      - {number: 2, title: Other, labels: [defect]}
+`)
+	// bug is renamed, then the deletion of defect once merged fails and the retry must not rename bug again.
+	opts := Opts{Concurrency: 1, FilePath: writeConf(t, `
+repos: [acme/widgets]
+config:
+  labels:
+    - {name: kind/bug, color: d73a4a, previously: [bug, defect]}
+`)}
+	flaky := &flakyClient{Client: client}
+	if err := Run(context.Background(), flaky, opts, testNow); err != nil {
+		t.Fatal(err)
+	}
+	if !flaky.failed {
+		t.Fatal("no deletion failed")
+	}
+	if got, want := labels(t, client, "acme/widgets"), []string{"kind/bug d73a4a "}; !slices.Equal(got, want) {
+		t.Errorf("labels are %q, want %q", got, want)
+	}
+	if got, want := issueLabels(t, client, "acme/widgets"), []string{"1:kind/bug", "2:kind/bug"}; !slices.Equal(got, want) {
+		t.Errorf("issue labels are %q, want %q", got, want)
+	}
+}
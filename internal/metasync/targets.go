@@ -0,0 +1,202 @@
+package metasync
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const topicPrefix = "topic:"
+
+type TargetsConf struct {
+	// Exclude are `org/<pattern>` or `topic:<org>/<topic>` targets which are never synced, even when listed in repos.
+	Exclude []Target `yaml:"exclude,omitempty"`
+	// IncludeArchived resolves patterns and topics to archived repositories too, they are read-only on GitHub.
+	IncludeArchived bool `yaml:"includeArchived,omitempty"`
+	// IncludeForks resolves patterns and topics to forks too.
+	IncludeForks bool `yaml:"includeForks,omitempty"`
+}
+
+// Target is a repository name, pattern or topic target written as a plain string, it keeps its position for
+// diagnostics.
+type Target struct {
+	Name string
+
+	pos position
+}
+
+func (t *Target) UnmarshalYAML(value *yaml.Node) error {
+	t.pos = positionOf(value)
+	return value.Decode(&t.Name)
+}
+
+func (t Target) MarshalYAML() (any, error) {
+	return t.Name, nil
+}
+
+func (t TargetsConf) isZero() bool {
+	return len(t.Exclude) == 0 && !t.IncludeArchived && !t.IncludeForks
+}
+
+// isTarget tells whether name has to be resolved by listing repositories rather than being a single repository.
+func isTarget(name string) bool {
+	return strings.HasPrefix(name, topicPrefix) || strings.ContainsAny(name, "*?[")
+}
+
+// topicTarget splits a `topic:<org>/<topic>` target, ok is false for the other targets.
+func topicTarget(target string) (org string, topic string, ok bool) {
+	rest, ok := strings.CutPrefix(target, topicPrefix)
+	if !ok {
+		return "", "", false
+	}
+	org, topic, _ = strings.Cut(rest, "/")
+	return org, topic, true
+}
+
+// targetOrg is the organization whose repositories are listed to resolve target.
+func targetOrg(target string) string {
+	if org, _, ok := topicTarget(target); ok {
+		return org
+	}
+	org, _, _ := utils.OrgRepo(target)
+	return org
+}
+
+func matchesTarget(target string, repo *api.Repository) bool {
+	if org, topic, ok := topicTarget(target); ok {
+		repoOrg, _, _ := utils.OrgRepo(repo.GetFullName())
+		return strings.EqualFold(org, repoOrg) && slices.Contains(repo.Topics, topic)
+	}
+	// Repository names are case-insensitive on GitHub.
+	ok, _ := path.Match(strings.ToLower(target), strings.ToLower(repo.GetFullName()))
+	return ok
+}
+
+type repoLister struct {
+	client api.Client
+	repos  map[string][]*api.Repository
+}
+
+func (l *repoLister) list(ctx context.Context, org string) ([]*api.Repository, error) {
+	if repos, ok := l.repos[org]; ok {
+		return repos, nil
+	}
+	repos, err := l.client.ListRepositories(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	l.repos[org] = repos
+	return repos, nil
+}
+
+// find returns the listed repository named name, nil if it wasn't listed.
+func (l *repoLister) find(ctx context.Context, name string) (*api.Repository, error) {
+	org, _, err := utils.OrgRepo(name)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := l.list(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repos {
+		if strings.EqualFold(r.GetFullName(), name) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveRepos expands the patterns and topics of the repos into the repositories they currently match and drops
+// the excluded ones. Repositories listed by name take precedence over the targets matching them.
+func (c ConfRoot) resolveRepos(ctx context.Context, client api.Client) ([]RepoDef, error) {
+	lister := &repoLister{client: client, repos: map[string][]*api.Repository{}}
+	explicit := map[string]bool{}
+	for _, def := range c.Repos {
+		if !isTarget(def.Name) {
+			explicit[strings.ToLower(def.Name)] = true
+		}
+	}
+
+	excluded := func(name string) (bool, error) {
+		for _, exclude := range c.Targets.Exclude {
+			target := exclude.Name
+			org, _, ok := topicTarget(target)
+			if !ok {
+				if matchesTarget(target, &api.Repository{FullName: &name}) {
+					return true, nil
+				}
+				continue
+			}
+			if !strings.EqualFold(org, targetOrg(name)) {
+				continue
+			}
+			repo, err := lister.find(ctx, name)
+			if err != nil {
+				return false, err
+			}
+			if repo != nil && matchesTarget(target, repo) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	seen := map[string]bool{}
+	var res []RepoDef
+	add := func(def RepoDef, name string) error {
+		if seen[strings.ToLower(name)] {
+			return nil
+		}
+		seen[strings.ToLower(name)] = true
+		skip, err := excluded(name)
+		if err != nil || skip {
+			if skip {
+				slog.LogAttrs(ctx, slog.LevelInfo, "excluding repo", slog.String("repo", name))
+			}
+			return err
+		}
+		def.Name = name
+		res = append(res, def)
+		return nil
+	}
+
+	for _, def := range c.Repos {
+		if !isTarget(def.Name) {
+			if err := add(def, def.Name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		repos, err := lister.list(ctx, targetOrg(def.Name))
+		if err != nil {
+			return nil, err
+		}
+		var matched []string
+		for _, r := range repos {
+			if !matchesTarget(def.Name, r) || explicit[strings.ToLower(r.GetFullName())] {
+				continue
+			}
+			if (r.GetArchived() && !c.Targets.IncludeArchived) || (r.GetFork() && !c.Targets.IncludeForks) {
+				continue
+			}
+			matched = append(matched, r.GetFullName())
+		}
+		sort.Strings(matched)
+		slog.LogAttrs(ctx, slog.LevelInfo, "resolved target", slog.String("target", def.Name), slog.Int("repos", len(matched)))
+		for _, name := range matched {
+			if err := add(def, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,104 @@
+package metasync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const targetsFixture = `
+repos:
+  acme/api:
+    topics: [platform]
+  acme/web:
+    topics: [frontend]
+  acme/old:
+    archived: true
+    topics: [platform]
+  other/api:
+    topics: [platform]
+  other/tools: {}
+`
+
+func TestResolveRepos(t *testing.T) {
+	tests := []struct {
+		name string
+		conf string
+		want []string
+	}{
+		{
+			name: "topic of an org",
+			conf: `repos: [topic:acme/platform]`,
+			want: []string{"acme/api"},
+		},
+		{
+			name: "topic isn't looked up in the orgs of other repos",
+			conf: `repos: [other/tools, topic:acme/platform]`,
+			want: []string{"other/tools", "acme/api"},
+		},
+		{
+			name: "archived included",
+			conf: `
+repos: [topic:acme/platform]
+targets: {includeArchived: true}
+`,
+			want: []string{"acme/api", "acme/old"},
+		},
+		{
+			name: "pattern",
+			conf: `repos: [ACME/*]`,
+			want: []string{"acme/api", "acme/web"},
+		},
+		{
+			name: "excluded by topic of the same org only",
+			conf: `
+repos: [acme/api, acme/web, other/api]
+targets: {exclude: [topic:acme/platform]}
+`,
+			want: []string{"acme/web", "other/api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf ConfRoot
+			if err := yaml.Unmarshal([]byte(tt.conf), &conf); err != nil {
+				t.Fatal(err)
+			}
+			defs, err := conf.resolveRepos(context.Background(), newFake(t, targetsFixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, def := range defs {
+				got = append(got, def.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "acme/api"},
+		{target: "acme/*"},
+		{target: "topic:acme/platform"},
+		{target: "topic:platform", want: `"topic:platform" is not in the topic:org/topic format`},
+		{target: "topic:acme/", want: `"topic:acme/" is not in the topic:org/topic format`},
+		{target: "topic:acme/a/b", want: `"topic:acme/a/b" is not in the topic:org/topic format`},
+		{target: "acme", want: `"acme" is not in the org/repo format`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := (ConfRoot{}).checkTarget(tt.target); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package metasync
+
+import (
+	_ "embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pmalek/github-pm-groomer/internal/utils"
+)
+
+// maxLabelDescription is the longest label description GitHub accepts.
+const maxLabelDescription = 100
+
+var colorRe = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// Schema is the JSON Schema of the meta-sync configuration file.
+//
+//go:embed meta-sync.schema.json
+var Schema []byte
+
+// Diagnostic is a problem found in the configuration.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+type validator struct {
+	diagnostics []Diagnostic
+}
+
+func (v *validator) report(pos position, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		File:    pos.file,
+		Line:    pos.line,
+		Column:  pos.column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks the configuration at path, including everything it includes, without calling GitHub. An error
+// is only returned when the configuration can't be loaded at all.
+func Validate(path string) ([]Diagnostic, error) {
+	conf, err := parseConf(path)
+	if err != nil {
+		return nil, err
+	}
+	return conf.validate(), nil
+}
+
+func invalidConfError(diagnostics []Diagnostic) error {
+	lines := make([]string, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		lines = append(lines, d.String())
+	}
+	return fmt.Errorf("invalid configuration:\n%s", strings.Join(lines, "\n"))
+}
+
+func (c ConfRoot) validate() []Diagnostic {
+	v := &validator{}
+	v.conf(c.Config, "config")
+	for _, org := range sortedKeys(c.Orgs) {
+		v.conf(c.Orgs[org].Conf, fmt.Sprintf("org %s", org))
+	}
+	for _, name := range sortedKeys(c.Groups) {
+		for _, pattern := range c.Groups[name].Repos {
+			if msg := checkPattern(pattern.Name); msg != "" {
+				v.report(pattern.pos, "group %s repos %s", name, msg)
+			}
+		}
+		v.conf(c.Groups[name].Conf, fmt.Sprintf("group %s", name))
+	}
+	for _, repo := range c.Repos {
+		if msg := c.checkTarget(repo.Name); msg != "" {
+			v.report(repo.pos, "repo %s", msg)
+		}
+		v.conf(repo.Conf, fmt.Sprintf("repo %s", repo.Name))
+	}
+	for _, target := range c.Targets.Exclude {
+		if msg := c.checkTarget(target.Name); msg != "" {
+			v.report(target.pos, "targets.exclude %s", msg)
+		}
+	}
+
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		a, b := v.diagnostics[i], v.diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return v.diagnostics
+}
+
+// checkTarget describes what's wrong with a repository or target, if anything.
+func (c ConfRoot) checkTarget(name string) string {
+	if org, topic, ok := topicTarget(name); ok {
+		if org == "" || topic == "" || strings.Contains(topic, "/") {
+			return fmt.Sprintf("%q is not in the topic:org/topic format", name)
+		}
+		return ""
+	}
+	return checkPattern(name)
+}
+
+// checkPattern describes what's wrong with an `org/repo` name or pattern, if anything.
+func checkPattern(name string) string {
+	if _, _, err := utils.OrgRepo(name); err != nil {
+		return fmt.Sprintf("%q is not in the org/repo format", name)
+	}
+	if _, err := path.Match(name, ""); err != nil {
+		return fmt.Sprintf("%q is not a valid pattern", name)
+	}
+	return ""
+}
+
+func (v *validator) conf(conf Conf, scope string) {
+	v.settings(conf.Settings)
+	v.branchProtection(conf.BranchProtection, scope)
+	v.access(conf.Access)
+	v.files(conf.Files, scope)
+	v.issueForms(conf.IssueForms, scope)
+	v.labelGroups(conf.LabelGroups, scope)
+	v.milestoneSchedules(conf.MilestoneSchedules, scope)
+	labels := map[string]LabelDef{}
+	for _, def := range conf.labels() {
+		if def.Name == "" {
+			v.report(def.pos, "label of %s has no name", scope)
+			continue
+		}
+		key := strings.ToLower(def.Name)
+		if prev, ok := labels[key]; ok {
+			v.report(def.pos, "label %q of %s is already defined at line %d as %q", def.Name, scope, prev.pos.line, prev.Name)
+		} else {
+			labels[key] = def
+		}
+		if def.Delete {
+			continue
+		}
+		if !colorRe.MatchString(def.Color) {
+			v.report(def.pos, "label %q has an invalid color %q, expected 6 hex digits without #", def.Name, def.Color)
+		}
+		if n := len([]rune(def.Description)); n > maxLabelDescription {
+			v.report(def.pos, "label %q has a description of %d characters, GitHub allows at most %d", def.Name, n, maxLabelDescription)
+		}
+	}
+
+	milestones := map[string]MilestoneDef{}
+	for _, def := range conf.Milestones {
+		if def.Title == "" {
+			v.report(def.pos, "milestone of %s has no title", scope)
+			continue
+		}
+		if prev, ok := milestones[def.Title]; ok {
+			v.report(def.pos, "milestone %q of %s is already defined at line %d", def.Title, scope, prev.pos.line)
+		} else {
+			milestones[def.Title] = def
+		}
+		if def.DueDate.invalid != "" {
+			v.report(def.pos, "milestone %q has an invalid dueDate %q, expected YYYY-MM-DD", def.Title, def.DueDate.invalid)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
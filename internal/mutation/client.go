@@ -0,0 +1,266 @@
+package mutation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+// Client wraps an api.Client, reads go through while every mutation is turned into an Op with its Before state
+// captured and passed to the handler instead of calling the API. It doesn't embed the client so a method added to
+// api.Client can't reach GitHub before it's turned into an Op.
+type Client struct {
+	client  api.Client
+	handler func(ctx context.Context, op Op) error
+}
+
+var _ api.Client = &Client{}
+
+func NewClient(client api.Client, handler func(ctx context.Context, op Op) error) *Client {
+	return &Client{client: client, handler: handler}
+}
+
+func (c *Client) handle(ctx context.Context, op Op) error {
+	if err := Capture(ctx, c.client, &op); err != nil {
+		return err
+	}
+	return c.handler(ctx, op)
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx)
+}
+
+func (c *Client) GetIssue(ctx context.Context, orgRepo string, issue int) (*api.Issue, error) {
+	return c.client.GetIssue(ctx, orgRepo, issue)
+}
+
+func (c *Client) GetIssues(ctx context.Context, orgRepo string, options api.IssueListOptions, page int) ([]*api.Issue, error) {
+	return c.client.GetIssues(ctx, orgRepo, options, page)
+}
+
+func (c *Client) ListLabels(ctx context.Context, orgRepo string) ([]*api.Label, error) {
+	return c.client.ListLabels(ctx, orgRepo)
+}
+
+func (c *Client) ListMilestones(ctx context.Context, orgRepo string) ([]*api.Milestone, error) {
+	return c.client.ListMilestones(ctx, orgRepo)
+}
+
+func (c *Client) ListRepositories(ctx context.Context, org string) ([]*api.Repository, error) {
+	return c.client.ListRepositories(ctx, org)
+}
+
+func (c *Client) GetRepository(ctx context.Context, orgRepo string) (*api.Repository, error) {
+	return c.client.GetRepository(ctx, orgRepo)
+}
+
+func (c *Client) ListRulesets(ctx context.Context, orgRepo string) ([]*api.Ruleset, error) {
+	return c.client.ListRulesets(ctx, orgRepo)
+}
+
+func (c *Client) ListTeamAccess(ctx context.Context, orgRepo string) ([]*api.Access, error) {
+	return c.client.ListTeamAccess(ctx, orgRepo)
+}
+
+func (c *Client) ListCollaboratorAccess(ctx context.Context, orgRepo string) ([]*api.Access, error) {
+	return c.client.ListCollaboratorAccess(ctx, orgRepo)
+}
+
+func (c *Client) GetFile(ctx context.Context, orgRepo string, path string, ref string) (*api.File, error) {
+	return c.client.GetFile(ctx, orgRepo, path, ref)
+}
+
+func (c *Client) UpdateLabels(ctx context.Context, orgRepo string, issue int, labels []string) error {
+	return c.handle(ctx, Op{Kind: UpdateIssueLabels, Repo: orgRepo, Issue: issue, After: &State{Labels: labels}})
+}
+
+func (c *Client) UpdateIssueState(ctx context.Context, orgRepo string, issue int, state string) error {
+	return c.handle(ctx, Op{Kind: UpdateIssueState, Repo: orgRepo, Issue: issue, After: &State{IssueState: state}})
+}
+
+func (c *Client) Comment(ctx context.Context, orgRepo string, issueNumber int, message string) error {
+	return c.handle(ctx, Op{Kind: Comment, Repo: orgRepo, Issue: issueNumber, After: &State{Comment: message}})
+}
+
+func (c *Client) CreateLabel(ctx context.Context, orgRepo string, label *api.Label) error {
+	return c.handle(ctx, Op{Kind: CreateLabel, Repo: orgRepo, After: &State{Label: FromLabel(label)}})
+}
+
+// UpdateLabel keeps the current value of the fields label leaves unset so the Op holds the full label.
+func (c *Client) UpdateLabel(ctx context.Context, orgRepo string, originalName string, label *api.Label) error {
+	op := Op{Kind: UpdateLabel, Repo: orgRepo, Name: originalName}
+	if err := Capture(ctx, c.client, &op); err != nil {
+		return err
+	}
+	after := FromLabel(label)
+	if op.Before != nil {
+		l := (*github.Label)(label)
+		if l.Name == nil {
+			after.Name = op.Before.Label.Name
+		}
+		if l.Color == nil {
+			after.Color = op.Before.Label.Color
+		}
+		if l.Description == nil {
+			after.Description = op.Before.Label.Description
+		}
+	}
+	op.After = &State{Label: after}
+	return c.handler(ctx, op)
+}
+
+func (c *Client) DeleteLabel(ctx context.Context, orgRepo string, name string) error {
+	return c.handle(ctx, Op{Kind: DeleteLabel, Repo: orgRepo, Name: name})
+}
+
+func (c *Client) CreateMilestone(ctx context.Context, orgRepo string, milestone *api.Milestone) error {
+	return c.handle(ctx, Op{Kind: CreateMilestone, Repo: orgRepo, After: &State{Milestone: FromMilestone(milestone)}})
+}
+
+// UpdateMilestone keeps the current value of the fields milestone leaves unset so the Op holds the full milestone.
+func (c *Client) UpdateMilestone(ctx context.Context, orgRepo string, number int, milestone *api.Milestone) error {
+	op := Op{Kind: UpdateMilestone, Repo: orgRepo, Number: number}
+	if err := Capture(ctx, c.client, &op); err != nil {
+		return err
+	}
+	after := FromMilestone(milestone)
+	if op.Before != nil {
+		m := (*github.Milestone)(milestone)
+		if m.Title == nil {
+			after.Title = op.Before.Milestone.Title
+		}
+		if m.State == nil {
+			after.State = op.Before.Milestone.State
+		}
+		if m.Description == nil {
+			after.Description = op.Before.Milestone.Description
+		}
+		if m.DueOn == nil {
+			after.DueOn = op.Before.Milestone.DueOn
+		}
+	}
+	op.After = &State{Milestone: after}
+	return c.handler(ctx, op)
+}
+
+func (c *Client) DeleteMilestone(ctx context.Context, orgRepo string, number int) error {
+	return c.handle(ctx, Op{Kind: DeleteMilestone, Repo: orgRepo, Number: number})
+}
+
+// UpdateRepository keeps the current value of the fields repository leaves unset so the Op holds all the settings.
+func (c *Client) UpdateRepository(ctx context.Context, orgRepo string, repository *api.Repository) error {
+	op := Op{Kind: UpdateRepository, Repo: orgRepo}
+	if err := Capture(ctx, c.client, &op); err != nil {
+		return err
+	}
+	after := *op.Before.Repository
+	r := (*github.Repository)(repository)
+	if r.Description != nil {
+		after.Description = r.GetDescription()
+	}
+	if r.Homepage != nil {
+		after.Homepage = r.GetHomepage()
+	}
+	if r.Topics != nil {
+		after.Topics = append([]string{}, r.Topics...)
+	}
+	if r.HasIssues != nil {
+		after.HasIssues = r.GetHasIssues()
+	}
+	if r.HasWiki != nil {
+		after.HasWiki = r.GetHasWiki()
+	}
+	if r.HasProjects != nil {
+		after.HasProjects = r.GetHasProjects()
+	}
+	if r.HasDiscussions != nil {
+		after.HasDiscussions = r.GetHasDiscussions()
+	}
+	if r.AllowMergeCommit != nil {
+		after.AllowMergeCommit = r.GetAllowMergeCommit()
+	}
+	if r.AllowSquashMerge != nil {
+		after.AllowSquashMerge = r.GetAllowSquashMerge()
+	}
+	if r.AllowRebaseMerge != nil {
+		after.AllowRebaseMerge = r.GetAllowRebaseMerge()
+	}
+	if r.DeleteBranchOnMerge != nil {
+		after.DeleteBranchOnMerge = r.GetDeleteBranchOnMerge()
+	}
+	if r.DefaultBranch != nil {
+		after.DefaultBranch = r.GetDefaultBranch()
+	}
+	op.After = &State{Repository: &after}
+	return c.handler(ctx, op)
+}
+
+func (c *Client) CreateRuleset(ctx context.Context, orgRepo string, ruleset *api.Ruleset) error {
+	return c.handle(ctx, Op{Kind: CreateRuleset, Repo: orgRepo, After: &State{Ruleset: FromRuleset(ruleset)}})
+}
+
+// UpdateRuleset and DeleteRuleset identify the ruleset by name in the Op.
+func (c *Client) UpdateRuleset(ctx context.Context, orgRepo string, id int64, ruleset *api.Ruleset) error {
+	name, err := c.rulesetName(ctx, orgRepo, id)
+	if err != nil {
+		return err
+	}
+	return c.handle(ctx, Op{Kind: UpdateRuleset, Repo: orgRepo, Name: name, After: &State{Ruleset: FromRuleset(ruleset)}})
+}
+
+func (c *Client) DeleteRuleset(ctx context.Context, orgRepo string, id int64) error {
+	name, err := c.rulesetName(ctx, orgRepo, id)
+	if err != nil {
+		return err
+	}
+	return c.handle(ctx, Op{Kind: DeleteRuleset, Repo: orgRepo, Name: name})
+}
+
+func (c *Client) rulesetName(ctx context.Context, orgRepo string, id int64) (string, error) {
+	rulesets, err := c.client.ListRulesets(ctx, orgRepo)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range rulesets {
+		if r.GetID() == id {
+			return r.Name, nil
+		}
+	}
+	return "", fmt.Errorf("ruleset #%d not found in %s", id, orgRepo)
+}
+
+// setAccess records granting access as a creation and changing it as an update.
+func (c *Client) setAccess(ctx context.Context, create Kind, update Kind, orgRepo string, name string, permission string) error {
+	op := Op{Kind: update, Repo: orgRepo, Name: name, After: &State{Permission: permission}}
+	if err := Capture(ctx, c.client, &op); err != nil {
+		return err
+	}
+	if op.Before == nil {
+		op.Kind = create
+	}
+	return c.handler(ctx, op)
+}
+
+func (c *Client) SetTeamAccess(ctx context.Context, orgRepo string, team string, permission string) error {
+	return c.setAccess(ctx, CreateTeamAccess, UpdateTeamAccess, orgRepo, team, permission)
+}
+
+func (c *Client) RemoveTeamAccess(ctx context.Context, orgRepo string, team string) error {
+	return c.handle(ctx, Op{Kind: DeleteTeamAccess, Repo: orgRepo, Name: team})
+}
+
+func (c *Client) SetCollaboratorAccess(ctx context.Context, orgRepo string, login string, permission string) error {
+	return c.setAccess(ctx, CreateCollaboratorAccess, UpdateCollaboratorAccess, orgRepo, login, permission)
+}
+
+func (c *Client) RemoveCollaboratorAccess(ctx context.Context, orgRepo string, login string) error {
+	return c.handle(ctx, Op{Kind: DeleteCollaboratorAccess, Repo: orgRepo, Name: login})
+}
+
+// ProposeFiles names the Op after the branch of the pull request.
+func (c *Client) ProposeFiles(ctx context.Context, orgRepo string, proposal *api.FileProposal) error {
+	return c.handle(ctx, Op{Kind: ProposeFiles, Repo: orgRepo, Name: proposal.Branch, After: &State{Proposal: FromProposal(proposal)}})
+}
@@ -0,0 +1,779 @@
+package mutation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+)
+
+type Kind string
+
+const (
+	UpdateIssueLabels Kind = "update-issue-labels"
+	UpdateIssueState  Kind = "update-issue-state"
+	Comment           Kind = "comment"
+	CreateLabel       Kind = "create-label"
+	UpdateLabel       Kind = "update-label"
+	DeleteLabel       Kind = "delete-label"
+	CreateMilestone   Kind = "create-milestone"
+	UpdateMilestone   Kind = "update-milestone"
+	DeleteMilestone   Kind = "delete-milestone"
+	UpdateRepository  Kind = "update-repository"
+	CreateRuleset     Kind = "create-ruleset"
+	UpdateRuleset     Kind = "update-ruleset"
+	DeleteRuleset     Kind = "delete-ruleset"
+	// The access kinds are about a team or an outside collaborator, named by Op.Name.
+	CreateTeamAccess         Kind = "create-team-access"
+	UpdateTeamAccess         Kind = "update-team-access"
+	DeleteTeamAccess         Kind = "delete-team-access"
+	CreateCollaboratorAccess Kind = "create-collaborator-access"
+	UpdateCollaboratorAccess Kind = "update-collaborator-access"
+	DeleteCollaboratorAccess Kind = "delete-collaborator-access"
+	ProposeFiles             Kind = "propose-files"
+)
+
+type Label struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+type Milestone struct {
+	Title       string     `json:"title"`
+	State       string     `json:"state"`
+	Description string     `json:"description"`
+	DueOn       *time.Time `json:"dueOn,omitempty"`
+}
+
+// Repository holds the settings of a repository which can be synced.
+type Repository struct {
+	Description         string   `json:"description"`
+	Homepage            string   `json:"homepage"`
+	Topics              []string `json:"topics"`
+	HasIssues           bool     `json:"hasIssues"`
+	HasWiki             bool     `json:"hasWiki"`
+	HasProjects         bool     `json:"hasProjects"`
+	HasDiscussions      bool     `json:"hasDiscussions"`
+	AllowMergeCommit    bool     `json:"allowMergeCommit"`
+	AllowSquashMerge    bool     `json:"allowSquashMerge"`
+	AllowRebaseMerge    bool     `json:"allowRebaseMerge"`
+	DeleteBranchOnMerge bool     `json:"deleteBranchOnMerge"`
+	DefaultBranch       string   `json:"defaultBranch"`
+}
+
+// Ruleset holds the rules of a branch ruleset which can be synced, other rules are not kept.
+type Ruleset struct {
+	Name string `json:"name"`
+	// Enforcement is active, evaluate or disabled.
+	Enforcement string `json:"enforcement"`
+	// Include and Exclude are the refs the ruleset applies to.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// BypassActors may push despite the rules, sorted by type and ID.
+	BypassActors            []BypassActor `json:"bypassActors"`
+	RequirePullRequest      bool          `json:"requirePullRequest"`
+	RequiredReviews         int           `json:"requiredReviews"`
+	DismissStaleReviews     bool          `json:"dismissStaleReviews"`
+	RequireCodeOwnerReviews bool          `json:"requireCodeOwnerReviews"`
+	RequiredStatusChecks    []string      `json:"requiredStatusChecks"`
+	StrictStatusChecks      bool          `json:"strictStatusChecks"`
+	LinearHistory           bool          `json:"linearHistory"`
+	BlockForcePushes        bool          `json:"blockForcePushes"`
+	BlockDeletions          bool          `json:"blockDeletions"`
+	RestrictUpdates         bool          `json:"restrictUpdates"`
+}
+
+// BypassActor is a role, team or app allowed to bypass a ruleset.
+type BypassActor struct {
+	// Type is RepositoryRole, Team, Integration or OrganizationAdmin.
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+	// Mode is always or pull_request.
+	Mode string `json:"mode"`
+}
+
+// File is the content of a file on the default branch of a repository.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Proposal is a pull request changing files, see api.FileProposal.
+type Proposal struct {
+	Branch  string `json:"branch"`
+	Message string `json:"message"`
+	Title   string `json:"title"`
+	Body    string `json:"body,omitempty"`
+	Files   []File `json:"files"`
+}
+
+func FromProposal(proposal *api.FileProposal) *Proposal {
+	res := &Proposal{Branch: proposal.Branch, Message: proposal.Message, Title: proposal.Title, Body: proposal.Body}
+	for _, f := range proposal.Files {
+		res.Files = append(res.Files, File{Path: f.Path, Content: string(f.Content)})
+	}
+	return res
+}
+
+func (p *Proposal) ToAPI() *api.FileProposal {
+	res := &api.FileProposal{Branch: p.Branch, Message: p.Message, Title: p.Title, Body: p.Body}
+	for _, f := range p.Files {
+		res.Files = append(res.Files, api.File{Path: f.Path, Content: []byte(f.Content)})
+	}
+	return res
+}
+
+// State is the part of an object a mutation is about, only the fields relevant to the kind of Op are set.
+type State struct {
+	Labels     []string    `json:"labels,omitempty"`
+	IssueState string      `json:"issueState,omitempty"`
+	Comment    string      `json:"comment,omitempty"`
+	Label      *Label      `json:"label,omitempty"`
+	Milestone  *Milestone  `json:"milestone,omitempty"`
+	Repository *Repository `json:"repository,omitempty"`
+	Ruleset    *Ruleset    `json:"ruleset,omitempty"`
+	// Permission is the access of a team or a collaborator.
+	Permission string `json:"permission,omitempty"`
+	// Files are the proposed files as they are on the default branch, missing ones are left out.
+	Files    []File    `json:"files,omitempty"`
+	Proposal *Proposal `json:"proposal,omitempty"`
+}
+
+// Op is a single change made through api.Client with the state of the object before and after it.
+type Op struct {
+	Kind Kind   `json:"kind"`
+	Repo string `json:"repo"`
+	// Issue, Name and Number identify the issue, label and milestone the Op is about.
+	Issue  int    `json:"issue,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Number int    `json:"number,omitempty"`
+	Before *State `json:"before,omitempty"`
+	After  *State `json:"after,omitempty"`
+}
+
+func (o Op) String() string {
+	switch {
+	case o.Issue != 0:
+		return fmt.Sprintf("%s %s#%d", o.Kind, o.Repo, o.Issue)
+	case o.Number != 0:
+		return fmt.Sprintf("%s %s milestone #%d", o.Kind, o.Repo, o.Number)
+	case o.Name != "":
+		return fmt.Sprintf("%s %s %q", o.Kind, o.Repo, o.Name)
+	case o.After != nil && o.After.Label != nil:
+		return fmt.Sprintf("%s %s %q", o.Kind, o.Repo, o.After.Label.Name)
+	case o.After != nil && o.After.Milestone != nil:
+		return fmt.Sprintf("%s %s milestone %q", o.Kind, o.Repo, o.After.Milestone.Title)
+	case o.After != nil && o.After.Ruleset != nil:
+		return fmt.Sprintf("%s %s %q", o.Kind, o.Repo, o.After.Ruleset.Name)
+	}
+	return fmt.Sprintf("%s %s", o.Kind, o.Repo)
+}
+
+func FromLabel(label *api.Label) *Label {
+	l := (*github.Label)(label)
+	return &Label{Name: l.GetName(), Color: l.GetColor(), Description: l.GetDescription()}
+}
+
+func (l *Label) ToAPI() *api.Label {
+	return &api.Label{Name: github.String(l.Name), Color: github.String(l.Color), Description: github.String(l.Description)}
+}
+
+func FromMilestone(milestone *api.Milestone) *Milestone {
+	m := (*github.Milestone)(milestone)
+	res := &Milestone{Title: m.GetTitle(), State: m.GetState(), Description: m.GetDescription()}
+	if m.DueOn != nil {
+		due := m.DueOn.Time
+		res.DueOn = &due
+	}
+	return res
+}
+
+func (m *Milestone) ToAPI() *api.Milestone {
+	res := &api.Milestone{Title: github.String(m.Title), State: github.String(m.State), Description: github.String(m.Description)}
+	if m.DueOn != nil {
+		res.DueOn = &github.Timestamp{Time: *m.DueOn}
+	}
+	return res
+}
+
+func FromRepository(repository *api.Repository) *Repository {
+	r := (*github.Repository)(repository)
+	return &Repository{
+		Description:         r.GetDescription(),
+		Homepage:            r.GetHomepage(),
+		Topics:              append([]string{}, r.Topics...),
+		HasIssues:           r.GetHasIssues(),
+		HasWiki:             r.GetHasWiki(),
+		HasProjects:         r.GetHasProjects(),
+		HasDiscussions:      r.GetHasDiscussions(),
+		AllowMergeCommit:    r.GetAllowMergeCommit(),
+		AllowSquashMerge:    r.GetAllowSquashMerge(),
+		AllowRebaseMerge:    r.GetAllowRebaseMerge(),
+		DeleteBranchOnMerge: r.GetDeleteBranchOnMerge(),
+		DefaultBranch:       r.GetDefaultBranch(),
+	}
+}
+
+func (r *Repository) ToAPI() *api.Repository {
+	return &api.Repository{
+		Description:         github.String(r.Description),
+		Homepage:            github.String(r.Homepage),
+		Topics:              append([]string{}, r.Topics...),
+		HasIssues:           github.Bool(r.HasIssues),
+		HasWiki:             github.Bool(r.HasWiki),
+		HasProjects:         github.Bool(r.HasProjects),
+		HasDiscussions:      github.Bool(r.HasDiscussions),
+		AllowMergeCommit:    github.Bool(r.AllowMergeCommit),
+		AllowSquashMerge:    github.Bool(r.AllowSquashMerge),
+		AllowRebaseMerge:    github.Bool(r.AllowRebaseMerge),
+		DeleteBranchOnMerge: github.Bool(r.DeleteBranchOnMerge),
+		DefaultBranch:       github.String(r.DefaultBranch),
+	}
+}
+
+func FromRuleset(ruleset *api.Ruleset) *Ruleset {
+	res := &Ruleset{
+		Name:                 ruleset.Name,
+		Enforcement:          ruleset.Enforcement,
+		Include:              []string{},
+		Exclude:              []string{},
+		BypassActors:         []BypassActor{},
+		RequiredStatusChecks: []string{},
+	}
+	if ruleset.Conditions != nil && ruleset.Conditions.RefName != nil {
+		res.Include = append(res.Include, ruleset.Conditions.RefName.Include...)
+		res.Exclude = append(res.Exclude, ruleset.Conditions.RefName.Exclude...)
+	}
+	for _, actor := range ruleset.BypassActors {
+		res.BypassActors = append(res.BypassActors, BypassActor{Type: actor.GetActorType(), ID: actor.GetActorID(), Mode: actor.GetBypassMode()})
+	}
+	SortBypassActors(res.BypassActors)
+	for _, rule := range ruleset.Rules {
+		switch rule.Type {
+		case "pull_request":
+			params := github.PullRequestRuleParameters{}
+			if rule.Parameters != nil {
+				_ = json.Unmarshal(*rule.Parameters, &params)
+			}
+			res.RequirePullRequest = true
+			res.RequiredReviews = params.RequiredApprovingReviewCount
+			res.DismissStaleReviews = params.DismissStaleReviewsOnPush
+			res.RequireCodeOwnerReviews = params.RequireCodeOwnerReview
+		case "required_status_checks":
+			params := github.RequiredStatusChecksRuleParameters{}
+			if rule.Parameters != nil {
+				_ = json.Unmarshal(*rule.Parameters, &params)
+			}
+			for _, check := range params.RequiredStatusChecks {
+				res.RequiredStatusChecks = append(res.RequiredStatusChecks, check.Context)
+			}
+			res.StrictStatusChecks = params.StrictRequiredStatusChecksPolicy
+		case "required_linear_history":
+			res.LinearHistory = true
+		case "non_fast_forward":
+			res.BlockForcePushes = true
+		case "deletion":
+			res.BlockDeletions = true
+		case "update":
+			res.RestrictUpdates = true
+		}
+	}
+	return res
+}
+
+func (r *Ruleset) ToAPI() *api.Ruleset {
+	res := &api.Ruleset{
+		Name:         r.Name,
+		Target:       github.String("branch"),
+		Enforcement:  r.Enforcement,
+		BypassActors: []*github.BypassActor{},
+		Conditions: &github.RulesetConditions{
+			RefName: &github.RulesetRefConditionParameters{Include: append([]string{}, r.Include...), Exclude: append([]string{}, r.Exclude...)},
+		},
+	}
+	// Journals written before enforcement was recorded only hold active rulesets.
+	if res.Enforcement == "" {
+		res.Enforcement = "active"
+	}
+	for _, actor := range r.BypassActors {
+		res.BypassActors = append(res.BypassActors, &github.BypassActor{
+			ActorID:    github.Int64(actor.ID),
+			ActorType:  github.String(actor.Type),
+			BypassMode: github.String(actor.Mode),
+		})
+	}
+	if r.RequirePullRequest {
+		res.Rules = append(res.Rules, github.NewPullRequestRule(&github.PullRequestRuleParameters{
+			RequiredApprovingReviewCount: r.RequiredReviews,
+			DismissStaleReviewsOnPush:    r.DismissStaleReviews,
+			RequireCodeOwnerReview:       r.RequireCodeOwnerReviews,
+		}))
+	}
+	if len(r.RequiredStatusChecks) > 0 {
+		params := &github.RequiredStatusChecksRuleParameters{StrictRequiredStatusChecksPolicy: r.StrictStatusChecks}
+		for _, check := range r.RequiredStatusChecks {
+			params.RequiredStatusChecks = append(params.RequiredStatusChecks, github.RuleRequiredStatusChecks{Context: check})
+		}
+		res.Rules = append(res.Rules, github.NewRequiredStatusChecksRule(params))
+	}
+	if r.LinearHistory {
+		res.Rules = append(res.Rules, github.NewRequiredLinearHistoryRule())
+	}
+	if r.BlockForcePushes {
+		res.Rules = append(res.Rules, github.NewNonFastForwardRule())
+	}
+	if r.BlockDeletions {
+		res.Rules = append(res.Rules, github.NewDeletionRule())
+	}
+	if r.RestrictUpdates {
+		res.Rules = append(res.Rules, github.NewUpdateRule(nil))
+	}
+	return res
+}
+
+// SortBypassActors sorts actors by type and ID, GitHub doesn't keep their order.
+func SortBypassActors(actors []BypassActor) {
+	sort.Slice(actors, func(i, j int) bool {
+		if actors[i].Type != actors[j].Type {
+			return actors[i].Type < actors[j].Type
+		}
+		return actors[i].ID < actors[j].ID
+	})
+}
+
+func findRuleset(ctx context.Context, client api.Client, repo string, name string) (*api.Ruleset, error) {
+	rulesets, err := client.ListRulesets(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rulesets {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// rulesetID returns the ID of the ruleset named name, rulesets are identified by name in operations as their IDs
+// change when they get recreated.
+func rulesetID(ctx context.Context, client api.Client, repo string, name string) (int64, error) {
+	ruleset, err := findRuleset(ctx, client, repo, name)
+	if err != nil {
+		return 0, err
+	}
+	if ruleset == nil {
+		return 0, fmt.Errorf("ruleset %q not found in %s", name, repo)
+	}
+	return ruleset.GetID(), nil
+}
+
+func findAccess(grants []*api.Access, name string) *api.Access {
+	for _, a := range grants {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+func isTeamAccess(kind Kind) bool {
+	return kind == CreateTeamAccess || kind == UpdateTeamAccess || kind == DeleteTeamAccess
+}
+
+// captureAccess returns the current access of the team or collaborator op is about, nil if it has none.
+func captureAccess(ctx context.Context, client api.Client, op Op) (*State, error) {
+	list := client.ListCollaboratorAccess
+	if isTeamAccess(op.Kind) {
+		list = client.ListTeamAccess
+	}
+	grants, err := list(ctx, op.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if a := findAccess(grants, op.Name); a != nil {
+		return &State{Permission: a.Permission}, nil
+	}
+	return nil, nil
+}
+
+func issueLabels(issue *api.Issue) []string {
+	labels := []string{}
+	for _, l := range issue.Labels {
+		labels = append(labels, l.GetName())
+	}
+	return labels
+}
+
+func findLabel(ctx context.Context, client api.Client, repo string, name string) (*api.Label, error) {
+	labels, err := client.ListLabels(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range labels {
+		if l.Name != nil && strings.EqualFold(*l.Name, name) {
+			return l, nil
+		}
+	}
+	return nil, nil
+}
+
+func findMilestone(ctx context.Context, client api.Client, repo string, match func(*api.Milestone) bool) (*api.Milestone, error) {
+	milestones, err := client.ListMilestones(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range milestones {
+		if match(m) {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// Capture reads the current state of the object op is about and sets it as op.Before.
+func Capture(ctx context.Context, client api.Client, op *Op) error {
+	switch op.Kind {
+	case UpdateIssueLabels, UpdateIssueState:
+		issue, err := client.GetIssue(ctx, op.Repo, op.Issue)
+		if err != nil {
+			return err
+		}
+		op.Before = &State{Labels: issueLabels(issue), IssueState: (*github.Issue)(issue).GetState()}
+	case UpdateLabel, DeleteLabel:
+		label, err := findLabel(ctx, client, op.Repo, op.Name)
+		if err != nil {
+			return err
+		}
+		if label != nil {
+			op.Before = &State{Label: FromLabel(label)}
+		}
+	case UpdateMilestone, DeleteMilestone:
+		milestone, err := findMilestone(ctx, client, op.Repo, func(m *api.Milestone) bool {
+			return m.Number != nil && *m.Number == op.Number
+		})
+		if err != nil {
+			return err
+		}
+		if milestone != nil {
+			op.Before = &State{Milestone: FromMilestone(milestone)}
+		}
+	case UpdateRepository:
+		repository, err := client.GetRepository(ctx, op.Repo)
+		if err != nil {
+			return err
+		}
+		op.Before = &State{Repository: FromRepository(repository)}
+	case UpdateRuleset, DeleteRuleset:
+		ruleset, err := findRuleset(ctx, client, op.Repo, op.Name)
+		if err != nil {
+			return err
+		}
+		if ruleset != nil {
+			op.Before = &State{Ruleset: FromRuleset(ruleset)}
+		}
+	case UpdateTeamAccess, DeleteTeamAccess, UpdateCollaboratorAccess, DeleteCollaboratorAccess:
+		before, err := captureAccess(ctx, client, *op)
+		if err != nil {
+			return err
+		}
+		op.Before = before
+	case ProposeFiles:
+		op.Before = &State{}
+		for _, f := range op.After.Proposal.Files {
+			cur, err := client.GetFile(ctx, op.Repo, f.Path, "")
+			if err != nil {
+				return err
+			}
+			if cur != nil {
+				op.Before.Files = append(op.Before.Files, File{Path: f.Path, Content: string(cur.Content)})
+			}
+		}
+	}
+	return nil
+}
+
+// Apply makes the change described by op.After.
+func Apply(ctx context.Context, client api.Client, op Op) error {
+	switch op.Kind {
+	case UpdateIssueLabels:
+		return client.UpdateLabels(ctx, op.Repo, op.Issue, op.After.Labels)
+	case UpdateIssueState:
+		return client.UpdateIssueState(ctx, op.Repo, op.Issue, op.After.IssueState)
+	case Comment:
+		return client.Comment(ctx, op.Repo, op.Issue, op.After.Comment)
+	case CreateLabel:
+		return client.CreateLabel(ctx, op.Repo, op.After.Label.ToAPI())
+	case UpdateLabel:
+		return client.UpdateLabel(ctx, op.Repo, op.Name, op.After.Label.ToAPI())
+	case DeleteLabel:
+		return client.DeleteLabel(ctx, op.Repo, op.Name)
+	case CreateMilestone:
+		return client.CreateMilestone(ctx, op.Repo, op.After.Milestone.ToAPI())
+	case UpdateMilestone:
+		return client.UpdateMilestone(ctx, op.Repo, op.Number, op.After.Milestone.ToAPI())
+	case DeleteMilestone:
+		return client.DeleteMilestone(ctx, op.Repo, op.Number)
+	case UpdateRepository:
+		return client.UpdateRepository(ctx, op.Repo, op.After.Repository.ToAPI())
+	case CreateRuleset:
+		return client.CreateRuleset(ctx, op.Repo, op.After.Ruleset.ToAPI())
+	case UpdateRuleset:
+		id, err := rulesetID(ctx, client, op.Repo, op.Name)
+		if err != nil {
+			return err
+		}
+		return client.UpdateRuleset(ctx, op.Repo, id, op.After.Ruleset.ToAPI())
+	case DeleteRuleset:
+		id, err := rulesetID(ctx, client, op.Repo, op.Name)
+		if err != nil {
+			return err
+		}
+		return client.DeleteRuleset(ctx, op.Repo, id)
+	case CreateTeamAccess, UpdateTeamAccess:
+		return client.SetTeamAccess(ctx, op.Repo, op.Name, op.After.Permission)
+	case DeleteTeamAccess:
+		return client.RemoveTeamAccess(ctx, op.Repo, op.Name)
+	case CreateCollaboratorAccess, UpdateCollaboratorAccess:
+		return client.SetCollaboratorAccess(ctx, op.Repo, op.Name, op.After.Permission)
+	case DeleteCollaboratorAccess:
+		return client.RemoveCollaboratorAccess(ctx, op.Repo, op.Name)
+	case ProposeFiles:
+		return client.ProposeFiles(ctx, op.Repo, op.After.Proposal.ToAPI())
+	}
+	return fmt.Errorf("unknown operation kind %q", op.Kind)
+}
+
+// LookupCreated sets the number GitHub gave to the milestone created by op.
+func LookupCreated(ctx context.Context, client api.Client, op *Op) error {
+	if op.Kind != CreateMilestone {
+		return nil
+	}
+	milestone, err := findMilestone(ctx, client, op.Repo, func(m *api.Milestone) bool {
+		return m.Title != nil && *m.Title == op.After.Milestone.Title
+	})
+	if err != nil {
+		return err
+	}
+	if milestone != nil {
+		op.Number = (*github.Milestone)(milestone).GetNumber()
+	}
+	return nil
+}
+
+// Inverse returns the Op reverting op, comments and proposed files can't be reverted and deleted labels come
+// back without their issues.
+func Inverse(op Op) (Op, bool) {
+	inv := Op{Repo: op.Repo, Issue: op.Issue, Number: op.Number, Before: op.After, After: op.Before}
+	switch op.Kind {
+	case UpdateIssueLabels, UpdateIssueState, UpdateRepository:
+		inv.Kind = op.Kind
+		return inv, op.Before != nil
+	case CreateLabel:
+		inv.Kind = DeleteLabel
+		inv.Name = op.After.Label.Name
+		return inv, true
+	case UpdateLabel:
+		inv.Kind = UpdateLabel
+		inv.Name = op.After.Label.Name
+		return inv, op.Before != nil
+	case DeleteLabel:
+		inv.Kind = CreateLabel
+		return inv, op.Before != nil
+	case CreateMilestone:
+		inv.Kind = DeleteMilestone
+		return inv, op.Number != 0
+	case UpdateMilestone:
+		inv.Kind = UpdateMilestone
+		return inv, op.Before != nil
+	case DeleteMilestone:
+		inv.Kind = CreateMilestone
+		inv.Number = 0
+		return inv, op.Before != nil
+	case CreateRuleset:
+		inv.Kind = DeleteRuleset
+		inv.Name = op.After.Ruleset.Name
+		return inv, true
+	case UpdateRuleset:
+		inv.Kind = UpdateRuleset
+		inv.Name = op.After.Ruleset.Name
+		return inv, op.Before != nil
+	case DeleteRuleset:
+		inv.Kind = CreateRuleset
+		return inv, op.Before != nil
+	case CreateTeamAccess:
+		inv.Kind = DeleteTeamAccess
+		inv.Name = op.Name
+		return inv, true
+	case CreateCollaboratorAccess:
+		inv.Kind = DeleteCollaboratorAccess
+		inv.Name = op.Name
+		return inv, true
+	case UpdateTeamAccess, UpdateCollaboratorAccess:
+		inv.Kind = op.Kind
+		inv.Name = op.Name
+		return inv, op.Before != nil
+	case DeleteTeamAccess:
+		inv.Kind = CreateTeamAccess
+		inv.Name = op.Name
+		return inv, op.Before != nil
+	case DeleteCollaboratorAccess:
+		inv.Kind = CreateCollaboratorAccess
+		inv.Name = op.Name
+		return inv, op.Before != nil
+	}
+	return Op{}, false
+}
+
+// ChangedError reports an object which isn't in the state an Op expects.
+type ChangedError struct {
+	msg string
+}
+
+func (e *ChangedError) Error() string {
+	return e.msg
+}
+
+func changedError(format string, args ...any) error {
+	return &ChangedError{msg: fmt.Sprintf(format, args...)}
+}
+
+// IsChanged tells whether err is a ChangedError rather than a failure to read the object.
+func IsChanged(err error) bool {
+	var changed *ChangedError
+	return errors.As(err, &changed)
+}
+
+// Verify checks the object op is about is still in the state op.Before was captured in.
+func Verify(ctx context.Context, client api.Client, op Op) error {
+	cur := Op{Kind: op.Kind, Repo: op.Repo, Issue: op.Issue, Name: op.Name, Number: op.Number, After: op.After}
+	switch op.Kind {
+	case CreateLabel:
+		label, err := findLabel(ctx, client, op.Repo, op.After.Label.Name)
+		if err != nil {
+			return err
+		}
+		if label != nil {
+			return changedError("%s: label %q was created since", op, op.After.Label.Name)
+		}
+		return nil
+	case CreateMilestone:
+		milestone, err := findMilestone(ctx, client, op.Repo, func(m *api.Milestone) bool {
+			return m.Title != nil && *m.Title == op.After.Milestone.Title
+		})
+		if err != nil {
+			return err
+		}
+		if milestone != nil {
+			return changedError("%s: milestone %q was created since", op, op.After.Milestone.Title)
+		}
+		return nil
+	case CreateRuleset:
+		ruleset, err := findRuleset(ctx, client, op.Repo, op.After.Ruleset.Name)
+		if err != nil {
+			return err
+		}
+		if ruleset != nil {
+			return changedError("%s: ruleset %q was created since", op, op.After.Ruleset.Name)
+		}
+		return nil
+	case CreateTeamAccess, CreateCollaboratorAccess:
+		cur, err := captureAccess(ctx, client, op)
+		if err != nil {
+			return err
+		}
+		if cur != nil {
+			return changedError("%s: access was granted since", op)
+		}
+		return nil
+	case Comment:
+		_, err := client.GetIssue(ctx, op.Repo, op.Issue)
+		return err
+	}
+	if err := Capture(ctx, client, &cur); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(normalize(cur.Before), normalize(op.Before)) {
+		return changedError("%s: changed since it was captured", op)
+	}
+	return nil
+}
+
+// VerifyApplied checks the object op is about is still in the state op left it in, only the fields op changed are
+// compared. Operations which can't be reverted aren't checked.
+func VerifyApplied(ctx context.Context, client api.Client, op Op) error {
+	inv, ok := Inverse(op)
+	if !ok {
+		return nil
+	}
+	switch op.Kind {
+	case UpdateIssueLabels, UpdateIssueState:
+		cur := Op{Kind: op.Kind, Repo: op.Repo, Issue: op.Issue}
+		if err := Capture(ctx, client, &cur); err != nil {
+			return err
+		}
+		if op.Kind == UpdateIssueLabels {
+			cur.Before.IssueState = ""
+		} else {
+			cur.Before.Labels = nil
+		}
+		if !reflect.DeepEqual(normalize(cur.Before), normalize(op.After)) {
+			return changedError("%s: changed since it was applied", op)
+		}
+		return nil
+	}
+	// The inverse expects the object to be as op left it.
+	if err := Verify(ctx, client, inv); err != nil {
+		if IsChanged(err) {
+			return changedError("%s: changed since it was applied", op)
+		}
+		return err
+	}
+	return nil
+}
+
+// normalize ignores differences which don't matter when comparing states.
+func normalize(s *State) *State {
+	if s == nil {
+		return nil
+	}
+	res := *s
+	res.Labels = append([]string{}, s.Labels...)
+	sort.Strings(res.Labels)
+	if s.Repository != nil {
+		r := *s.Repository
+		r.Topics = append([]string{}, r.Topics...)
+		sort.Strings(r.Topics)
+		res.Repository = &r
+	}
+	if s.Milestone != nil {
+		m := *s.Milestone
+		if m.DueOn != nil {
+			due := m.DueOn.UTC()
+			m.DueOn = &due
+		}
+		// Milestones are created open unless asked otherwise.
+		if m.State == "" {
+			m.State = "open"
+		}
+		res.Milestone = &m
+	}
+	if s.Ruleset != nil {
+		r := *s.Ruleset
+		if r.Enforcement == "" {
+			r.Enforcement = "active"
+		}
+		r.Exclude = append([]string{}, r.Exclude...)
+		r.BypassActors = append([]BypassActor{}, r.BypassActors...)
+		SortBypassActors(r.BypassActors)
+		res.Ruleset = &r
+	}
+	return &res
+}
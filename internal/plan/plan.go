@@ -0,0 +1,92 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/mutation"
+)
+
+// Plan is the exact list of changes a command would make, saved to be reviewed and applied later.
+type Plan struct {
+	CreatedAt  time.Time     `json:"createdAt"`
+	Command    string        `json:"command"`
+	Operations []mutation.Op `json:"operations"`
+}
+
+// Client wraps an api.Client and records mutations in a plan instead of making them.
+type Client struct {
+	*mutation.Client
+
+	mu  sync.Mutex
+	ops []mutation.Op
+}
+
+var _ api.Client = &Client{}
+
+func NewClient(client api.Client) *Client {
+	c := &Client{}
+	c.Client = mutation.NewClient(client, c.record)
+	return c
+}
+
+func (c *Client) record(ctx context.Context, op mutation.Op) error {
+	slog.LogAttrs(ctx, slog.LevelInfo, "planning operation", slog.String("op", op.String()))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ops = append(c.ops, op)
+	return nil
+}
+
+func (c *Client) Plan(command string, now time.Time) Plan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Plan{
+		CreatedAt:  now,
+		Command:    command,
+		Operations: append([]mutation.Op{}, c.ops...),
+	}
+}
+
+func Write(path string, p Plan) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func Read(path string) (Plan, error) {
+	p := Plan{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Apply executes the operations of the plan in order. Nothing is changed unless every object targeted by the
+// plan is still in the state it was in at planning time.
+func Apply(ctx context.Context, client api.Client, p Plan) error {
+	for _, op := range p.Operations {
+		if err := mutation.Verify(ctx, client, op); err != nil {
+			return fmt.Errorf("refusing to apply the plan: %w", err)
+		}
+	}
+	for _, op := range p.Operations {
+		slog.LogAttrs(ctx, slog.LevelInfo, "applying operation", slog.String("op", op.String()))
+		if err := mutation.Apply(ctx, client, op); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", op, err)
+		}
+	}
+	return nil
+}
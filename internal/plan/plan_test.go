@@ -0,0 +1,104 @@
+package plan
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v67/github"
+	"github.com/pmalek/github-pm-groomer/internal/github/api"
+	"github.com/pmalek/github-pm-groomer/internal/github/fake"
+)
+
+func TestApply(t *testing.T) {
+	fixture := fake.Fixture{Repos: map[string]fake.RepoFixture{
+		"org/repo": {
+			Labels: []fake.LabelFixture{{Name: "bug", Color: "ff0000"}, {Name: "stale", Color: "ededed"}},
+			Issues: []fake.IssueFixture{{Number: 1, Title: "issue", State: "open", Labels: []string{"bug"}}},
+		},
+	}}
+	tests := []struct {
+		name string
+		// external changes objects between planning and applying.
+		external func(ctx context.Context, client api.Client) error
+		wantErr  bool
+	}{
+		{name: "unchanged"},
+		{
+			name: "planned label changed",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.UpdateLabel(ctx, "org/repo", "bug", &api.Label{Color: github.String("123456")})
+			},
+			wantErr: true,
+		},
+		{
+			name: "planned label created",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.CreateLabel(ctx, "org/repo", &api.Label{Name: github.String("triage"), Color: github.String("123456")})
+			},
+			wantErr: true,
+		},
+		{
+			name: "planned issue changed",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.UpdateIssueState(ctx, "org/repo", 1, "closed")
+			},
+			wantErr: true,
+		},
+		{
+			name: "unrelated label changed",
+			external: func(ctx context.Context, client api.Client) error {
+				return client.DeleteLabel(ctx, "org/repo", "stale")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := fake.New(fixture)
+			planner := NewClient(backend)
+			if err := planner.UpdateLabel(ctx, "org/repo", "bug", &api.Label{Color: github.String("d73a4a")}); err != nil {
+				t.Fatal(err)
+			}
+			if err := planner.CreateLabel(ctx, "org/repo", &api.Label{Name: github.String("triage"), Color: github.String("fbca04")}); err != nil {
+				t.Fatal(err)
+			}
+			if err := planner.UpdateLabels(ctx, "org/repo", 1, []string{"bug", "triage"}); err != nil {
+				t.Fatal(err)
+			}
+			if labels, _ := backend.ListLabels(ctx, "org/repo"); len(labels) != 2 {
+				t.Fatalf("planning changed the labels to %v", labels)
+			}
+
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if err := Write(path, planner.Plan("test", time.Now())); err != nil {
+				t.Fatal(err)
+			}
+			p, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Operations) != 3 {
+				t.Fatalf("planned %d operations, want 3", len(p.Operations))
+			}
+			if tt.external != nil {
+				if err := tt.external(ctx, backend); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = Apply(ctx, backend, p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one: %v", err, tt.wantErr)
+			}
+			issue, err := backend.GetIssue(ctx, "org/repo", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if applied := len(issue.Labels) == 2; applied == tt.wantErr {
+				t.Errorf("issue has %d labels, the plan was applied: %v", len(issue.Labels), applied)
+			}
+		})
+	}
+}
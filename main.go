@@ -2,16 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/pmalek/github-pm-groomer/cmd"
+	"github.com/pmalek/github-pm-groomer/internal/metasync"
 )
 
+// driftExitCode tells scripts running `meta-sync diff` drift apart from failures.
+const driftExitCode = 2
+
 func main() {
 	ctx := context.Background()
 	if err := cmd.Execute(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, metasync.ErrDrift) {
+			os.Exit(driftExitCode)
+		}
 		os.Exit(1)
 	}
 }